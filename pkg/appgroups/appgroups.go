@@ -2,11 +2,19 @@ package appgroups
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/diff"
 	"github.com/fairwindsops/insights-cli/pkg/directory"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
 	"github.com/imroc/req/v3"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
@@ -23,70 +31,148 @@ func AddAppGroupsBranch(tree treeprint.Tree, appGroups []AppGroup) error {
 	return nil
 }
 
-// PushAppGroups pushes app-groups to insights
-func PushAppGroups(client *req.Client, pushDir, org string, deleteMissing, dryRun bool) error {
-	logrus.Debugln("Pushing app-groups")
+// Plan holds the app-groups changes that will be made by ApplyPlan, and a
+// fingerprint of the app-groups in Insights at the time the plan was built.
+type Plan struct {
+	Upserts           []AppGroup  `json:"upserts"`
+	Deletes           []AppGroup  `json:"deletes"`
+	Diffs             []diff.Diff `json:"diffs"`
+	FileAppGroupNames []string    `json:"fileAppGroupNames"`
+	RemoteFingerprint string      `json:"remoteFingerprint"`
+}
+
+// BuildPlan compares the app-groups in pushDir with those in Insights, and
+// returns the changes needed to make Insights match pushDir.
+// Deletions are only planned when deleteMissing is true.
+func BuildPlan(client *req.Client, pushDir, org string, deleteMissing bool) (*Plan, error) {
 	_, err := os.Stat(pushDir)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	existingAppGroups, err := FetchAppGroups(client, org)
+	if err != nil {
+		return nil, fmt.Errorf("error during API call: %w", err)
+	}
+
+	upserts, deletes, diffs, fileNames, err := compareAppGroups(pushDir, existingAppGroups)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare and push app-groups to Insights: %w", err)
+	}
+	fingerprint, err := utils.Fingerprint(existingAppGroups)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint app-groups: %w", err)
 	}
+	plan := Plan{Upserts: upserts, Diffs: diffs, FileAppGroupNames: fileNames, RemoteFingerprint: fingerprint}
+	if deleteMissing {
+		plan.Deletes = deletes
+	}
+	return &plan, nil
+}
 
+// BuildRestorePlan returns the changes needed to make the app-groups in Insights
+// match those given, which were previously retrieved with FetchAppGroups. App-groups
+// that are not given are deleted.
+func BuildRestorePlan(client *req.Client, org string, desired []AppGroup) (*Plan, error) {
 	existingAppGroups, err := FetchAppGroups(client, org)
 	if err != nil {
-		return fmt.Errorf("error during API call: %w", err)
+		return nil, fmt.Errorf("error during API call: %w", err)
 	}
+	fingerprint, err := utils.Fingerprint(existingAppGroups)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint app-groups: %w", err)
+	}
+	upserts, deletes, diffs := getAppGroupsDifferences(desired, existingAppGroups)
+	return &Plan{Upserts: upserts, Deletes: deletes, Diffs: diffs, RemoteFingerprint: fingerprint}, nil
+}
 
-	upserts, deletes, err := compareAppGroups(pushDir, existingAppGroups)
+// RemoteFingerprint returns the fingerprint of the app-groups in Insights
+// covered by the plan, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	existingAppGroups, err := FetchAppGroups(client, org)
+	if err != nil {
+		return "", fmt.Errorf("error during API call: %w", err)
+	}
+	fingerprint, err := utils.Fingerprint(existingAppGroups)
 	if err != nil {
-		return fmt.Errorf("unable to compare and push app-groups to Insights: %w", err)
+		return "", fmt.Errorf("unable to fingerprint app-groups: %w", err)
 	}
+	return fingerprint, nil
+}
 
-	for _, appGroup := range upserts {
+// VerifyPlan returns an error if the app-groups in Insights have changed since
+// the plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
+	if err != nil {
+		return err
+	}
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("app-groups in Insights have changed since the plan was created")
+	}
+	return nil
+}
+
+// ApplyPlan makes the changes described by the plan in Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	limit := workerpool.Concurrency()
+	if dryRun {
+		limit = 1
+	}
+	err := deletion.Confirm("app-groups", lo.Map(plan.Deletes, func(i AppGroup, _ int) string { return i.Name }), dryRun)
+	if err != nil {
+		return err
+	}
+	err = workerpool.Run(limit, plan.Upserts, func(appGroup AppGroup) error {
 		logrus.Infof("upsert app-group: %s", appGroup.Name)
-		if !dryRun {
-			err = upsertAppGroup(client, org, appGroup)
-			if err != nil {
-				return fmt.Errorf("error while upsert app-group %s to Fairwinds Insights: %w", appGroup.Name, err)
-			}
+		if dryRun {
+			return diff.Print(os.Stdout, diff.ForName(plan.Diffs, appGroup.Name))
+		}
+		err := upsertAppGroup(client, org, appGroup)
+		if err != nil {
+			return fmt.Errorf("error while upsert app-group %s to Fairwinds Insights: %w", appGroup.Name, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if deleteMissing {
-		for _, appGroupForDelete := range deletes {
-			logrus.Infof("Deleting app-group: %s", appGroupForDelete.Name)
-			if !dryRun {
-				err = deleteAppGroup(client, org, appGroupForDelete)
-				if err != nil {
-					return fmt.Errorf("error while deleting app-group %s from insights: %w", appGroupForDelete.Name, err)
-				}
-			}
+	return workerpool.Run(limit, plan.Deletes, func(appGroupForDelete AppGroup) error {
+		logrus.Infof("Deleting app-group: %s", appGroupForDelete.Name)
+		if dryRun {
+			return nil
 		}
-	}
-	logrus.Debugln("Done pushing app-groups")
-	return nil
+		err := deleteAppGroup(client, org, appGroupForDelete)
+		if err != nil {
+			return fmt.Errorf("error while deleting app-group %s from insights: %w", appGroupForDelete.Name, err)
+		}
+		return nil
+	})
 }
 
 // compareAppGroups compares a folder vs the app-groups returned by the API.
-func compareAppGroups(folder string, existingAppGroups []AppGroup) (upserts, deletes []AppGroup, err error) {
-	files, err := directory.ScanFolder(folder)
+func compareAppGroups(folder string, existingAppGroups []AppGroup) (upserts, deletes []AppGroup, diffs []diff.Diff, fileNames []string, err error) {
+	files, err := overlay.ScanFolder(folder, directory.ScanFolder)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error scanning directory: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error scanning directory: %w", err)
 
 	}
 	fileAppGroups, err := getAppGroupsFromFiles(files)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error reading app-groups from files: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error reading app-groups from files: %w", err)
 
 	}
-	upserts, deletes = getAppGroupsDifferences(fileAppGroups, existingAppGroups)
-	return upserts, deletes, nil
+	upserts, deletes, diffs = getAppGroupsDifferences(fileAppGroups, existingAppGroups)
+	fileNames = lo.Map(fileAppGroups, func(i AppGroup, _ int) string { return i.Name })
+	return upserts, deletes, diffs, fileNames, nil
 }
 
 func getAppGroupsFromFiles(files map[string][]string) ([]AppGroup, error) {
 	var appGroups []AppGroup
 	for _, appGroupsFiles := range files {
 		for _, filePath := range appGroupsFiles {
-			content, err := os.ReadFile(filePath)
+			content, err := overlay.ReadFile(filePath)
 			if err != nil {
 				return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
 			}
@@ -107,15 +193,28 @@ func getAppGroupsFromFiles(files map[string][]string) ([]AppGroup, error) {
 	return appGroups, nil
 }
 
-func getAppGroupsDifferences(fileAppGroups, existingAppGroups []AppGroup) (upserts, deletes []AppGroup) {
+// getAppGroupsDifferences returns the app-groups to upsert and delete to make
+// existingAppGroups match fileAppGroups. AppGroups not selected by the push
+// filter are left alone, and only owned app-groups are deleted.
+func getAppGroupsDifferences(fileAppGroups, existingAppGroups []AppGroup) (upserts, deletes []AppGroup, diffs []diff.Diff) {
+	fileAppGroups = lo.Filter(fileAppGroups, func(i AppGroup, _ int) bool { return filter.Matches(i.Name, i.Labels) })
 	fileAppGroupsByName := lo.KeyBy(fileAppGroups, func(i AppGroup) string { return i.Name })
 	existingAppGroupsByName := lo.KeyBy(existingAppGroups, func(i AppGroup) string { return i.Name })
 
 	for name, fileAppGroup := range fileAppGroupsByName {
 		if existingAppGroup, found := existingAppGroupsByName[name]; found {
-			if !reflect.DeepEqual(fileAppGroup, existingAppGroup) {
+			compared := existingAppGroup
+			// labels are not kept in Insights
+			compared.Labels = fileAppGroup.Labels
+			if !reflect.DeepEqual(fileAppGroup, compared) {
 				// only update if the app-group has changed
 				upserts = append(upserts, fileAppGroup)
+				d, err := diff.YAML("app-group", name, existingAppGroup, fileAppGroup)
+				if err != nil {
+					logrus.Warnf("unable to diff app-group %s: %v", name, err)
+					continue
+				}
+				diffs = append(diffs, d)
 			}
 		} else {
 			upserts = append(upserts, fileAppGroup)
@@ -123,9 +222,11 @@ func getAppGroupsDifferences(fileAppGroups, existingAppGroups []AppGroup) (upser
 	}
 
 	for name, existingAppGroup := range existingAppGroupsByName {
-		if _, ok := fileAppGroupsByName[name]; !ok {
+		// labels are only in local files, so app-groups only in Insights are not
+		// deleted while a label selector is set
+		if _, ok := fileAppGroupsByName[name]; !ok && ownership.Owns(name) && filter.MatchesRemote(existingAppGroup.Name) {
 			deletes = append(deletes, existingAppGroup)
 		}
 	}
-	return upserts, deletes
+	return upserts, deletes, diffs
 }
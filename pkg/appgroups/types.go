@@ -8,6 +8,9 @@ type AppGroup struct {
 	Name string       `json:"name,omitempty" yaml:"name,omitempty"`
 	Spec AppGroupSpec `json:"spec" yaml:"spec"`
 	Type string       `json:"type,omitempty" yaml:"type,omitempty"`
+	// Labels are used to select resources with push --selector. They are
+	// only kept in local files and never sent to Insights.
+	Labels map[string]string `json:"-" yaml:"labels,omitempty"`
 }
 
 func (a AppGroup) GetYamlBytes() ([]byte, error) {
@@ -0,0 +1,55 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan file>",
+	Short: "Apply a saved plan to Insights.",
+	Long:  "Execute exactly the changes saved by the plan command. The plan is not applied if the resources it covers have changed in Insights since it was created.",
+	Example: `
+	insights-cli plan -d . -o plan.json
+	insights-cli apply plan.json`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		org := configurationObject.Options.Organization
+		p, err := plan.Load(args[0])
+		if err != nil {
+			logrus.Fatalf("Unable to load plan: %v", err)
+		}
+		err = plan.Print(os.Stdout, *p)
+		if err != nil {
+			logrus.Fatalf("Unable to print plan: %v", err)
+		}
+		err = plan.Apply(client, org, *p)
+		if err != nil {
+			logrus.Fatalf("Unable to apply plan: %v", err)
+		}
+		logrus.Infoln("Apply succeeded.")
+	},
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/organizations"
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/policymappings"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/teams"
+)
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authStatusCmd)
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Check authentication with Insights.",
+	Long:  "Check the Insights token and what it can access.",
+	Run: func(cmd *cobra.Command, args []string) {
+		logrus.Error("Please specify a sub-command.")
+		err := cmd.Help()
+		if err != nil {
+			logrus.Error(err)
+		}
+		os.Exit(1)
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the Insights token is valid, and what it can access.",
+	Long:  "Show the Insights hostname and organization in use, whether the token is valid for the organization, and which resources it can read. Write access is not checked, as that would change Insights. Exits with an error if the token is not valid, so CI can fail before a push.",
+	Example: `
+	# Check the token before pushing
+	insights-cli auth status && insights-cli push all
+
+	# Check the token of a profile
+	insights-cli auth status --profile staging`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run:    runAuthStatus,
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:    "whoami",
+	Short:  "Show whether the Insights token is valid, and what it can access.",
+	Long:   "Show whether the Insights token is valid, and which resources it can read. This is the same as the auth status command.",
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run:    runAuthStatus,
+}
+
+// capability is something a token may be allowed to read, which is checked
+// with a read-only request. Write access is never checked, as a request that
+// could show it would change Insights.
+type capability struct {
+	description string
+	check       func(client *req.Client, org string) error
+}
+
+var capabilities = []capability{
+	{"read OPA policies", func(client *req.Client, org string) error {
+		_, err := opa.GetChecks(client, org)
+		return err
+	}},
+	{"read automation rules", func(client *req.Client, org string) error {
+		_, err := rules.FetchRules(client, org)
+		return err
+	}},
+	{"read policies configuration", func(client *req.Client, org string) error {
+		_, err := policies.GetPolicies(client, org)
+		return err
+	}},
+	{"read app-groups", func(client *req.Client, org string) error {
+		_, err := appgroups.FetchAppGroups(client, org)
+		return err
+	}},
+	{"read policy-mappings", func(client *req.Client, org string) error {
+		_, err := policymappings.FetchPolicyMappings(client, org)
+		return err
+	}},
+	{"read Kyverno policies", func(client *req.Client, org string) error {
+		_, err := kyverno.FetchKyvernoPolicies(client, org)
+		return err
+	}},
+	{"read teams", func(client *req.Client, org string) error {
+		_, err := teams.ListTeams(client, org)
+		return err
+	}},
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) {
+	org := configurationObject.Options.Organization
+	fmt.Printf("Hostname: %s\n", configurationObject.Options.Hostname)
+	fmt.Printf("Organization: %s\n", org)
+	if activeProfile != "" {
+		fmt.Printf("Profile: %s\n", activeProfile)
+	}
+	_, err := organizations.GetOrganization(client, org)
+	if errors.Is(err, organizations.ErrUnauthorized) {
+		fmt.Println("Token: invalid")
+		logrus.Fatalf("The Insights token is not valid for organization %s: %v", org, err)
+	}
+	if err != nil {
+		logrus.Fatalf("Unable to check the Insights token: %v", err)
+	}
+	fmt.Println("Token: valid")
+	fmt.Println("Capabilities (read access only):")
+	for _, c := range capabilities {
+		err := c.check(client, org)
+		if err != nil {
+			logrus.Debugf("Unable to %s: %v", c.description, err)
+			fmt.Printf("  - %s: no\n", c.description)
+			continue
+		}
+		fmt.Printf("  - %s: yes\n", c.description)
+	}
+	fmt.Println("  - write resources: not checked")
+}
@@ -15,6 +15,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -57,24 +58,16 @@ type nameable interface {
 var filenameRegex = regexp.MustCompile("[^A-Za-z0-9]+")
 
 func saveEntitiesLocally[T nameable](saveDir string, entities []T, overrideLocalFiles bool, skipFilesPatterns []string) (int, error) {
-	_, err := os.Stat(saveDir)
-	if err != nil {
+	ok, err := prepareDownloadDirectory(saveDir, overrideLocalFiles, skipFilesPatterns)
+	if err != nil || !ok {
 		return 0, err
 	}
-	isEmpty, err := IsEmpty(saveDir)
-	if err != nil {
-		return 0, fmt.Errorf("error checking if directory %s is empty: %w", saveDir, err)
-	}
-	if !isEmpty && !overrideLocalFiles {
-		logrus.Warnf("directory %s must be empty, use --override to override local files", saveDir)
-		return 0, nil
-	}
-
-	err = purgeDirectory(saveDir, skipFilesPatterns)
-	if err != nil {
-		return 0, fmt.Errorf("could not purge directory %s: %w", saveDir, err)
-	}
+	return writeEntities(saveDir, entities)
+}
 
+// writeEntities writes a YAML file per entity to saveDir, which has already
+// been prepared.
+func writeEntities[T nameable](saveDir string, entities []T) (int, error) {
 	var saved int
 	for _, e := range entities {
 		filename := formatFilename(e.GetName())
@@ -93,7 +86,53 @@ func saveEntitiesLocally[T nameable](saveDir string, entities []T, overrideLocal
 	return saved, nil
 }
 
-// remove all contents of a directory except files matching skipFilesPatterns
+// prepareDownloadDirectory empties saveDir, except files matching
+// skipFilesPatterns, and returns false if saveDir is not empty and
+// overrideLocalFiles is not set.
+func prepareDownloadDirectory(saveDir string, overrideLocalFiles bool, skipFilesPatterns []string) (bool, error) {
+	_, err := os.Stat(saveDir)
+	if err != nil {
+		return false, err
+	}
+	isEmpty, err := IsEmpty(saveDir)
+	if err != nil {
+		return false, fmt.Errorf("error checking if directory %s is empty: %w", saveDir, err)
+	}
+	if !isEmpty && !overrideLocalFiles {
+		logrus.Warnf("directory %s must be empty, use --override to override local files", saveDir)
+		return false, nil
+	}
+
+	err = purgeDirectory(saveDir, skipFilesPatterns)
+	if err != nil {
+		return false, fmt.Errorf("could not purge directory %s: %w", saveDir, err)
+	}
+	return true, nil
+}
+
+// prepareDownloadFile returns false if fileName already exists and
+// overrideLocalFiles is not set.
+func prepareDownloadFile(fileName string, overrideLocalFiles bool) (bool, error) {
+	_, err := os.Stat(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !overrideLocalFiles {
+		logrus.Warnf("file %s already exists, use --override to override local files", fileName)
+		return false, nil
+	}
+	return true, nil
+}
+
+// fixtureFilePatterns match the validation test cases kept alongside
+// downloaded policies, which are not removed by --override.
+var fixtureFilePatterns = []string{".success.yaml", ".failure.yaml", ".expected.yaml"}
+
+// remove all contents of a directory except files matching skipFilesPatterns,
+// and the sub-directories holding them
 func purgeDirectory(saveDir string, skipFilesPatterns []string) error {
 	// If no skip patterns, remove everything
 	if len(skipFilesPatterns) == 0 {
@@ -127,6 +166,18 @@ func purgeDirectory(saveDir string, skipFilesPatterns []string) error {
 			}
 		}
 
+		if !shouldSkip && entry.IsDir() {
+			err := purgeDirectory(entryPath, skipFilesPatterns)
+			if err != nil {
+				return err
+			}
+			isEmpty, err := IsEmpty(entryPath)
+			if err != nil {
+				return fmt.Errorf("error checking if directory %s is empty: %w", entryPath, err)
+			}
+			shouldSkip = !isEmpty
+		}
+
 		// Delete if it doesn't match any skip pattern
 		if !shouldSkip {
 			err := os.RemoveAll(entryPath)
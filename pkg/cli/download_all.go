@@ -0,0 +1,171 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/policymappings"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+)
+
+var downloadAllOPASubDir, downloadAllRulesSubDir, downloadAllAppGroupsSubDir, downloadAllPolicyMappingsSubDir, downloadAllKyvernoPoliciesSubDir string
+
+func init() {
+	downloadAllCmd.PersistentFlags().StringVarP(&downloadAllOPASubDir, "download-opa-subdirectory", "", defaultPushOPASubDir, "Sub-directory within download-directory, to download OPA policies.")
+	downloadAllCmd.PersistentFlags().StringVarP(&downloadAllRulesSubDir, "download-rules-subdirectory", "", defaultPushRulesSubDir, "Sub-directory within download-directory, to download automation rules.")
+	downloadAllCmd.PersistentFlags().StringVarP(&downloadAllAppGroupsSubDir, "download-app-groups-subdirectory", "", defaultPushAppGroupsSubDir, "Sub-directory within download-directory, to download App Groups.")
+	downloadAllCmd.PersistentFlags().StringVarP(&downloadAllPolicyMappingsSubDir, "download-policy-mappings-subdirectory", "", defaultPushPolicyMappingsSubDir, "Sub-directory within download-directory, to download Policy Mappings.")
+	downloadAllCmd.PersistentFlags().StringVarP(&downloadAllKyvernoPoliciesSubDir, "download-kyverno-policies-subdirectory", "", defaultPushKyvernoPoliciesSubDir, "Sub-directory within download-directory, to download Kyverno policies.")
+	downloadCmd.AddCommand(downloadAllCmd)
+}
+
+// downloadAllResource is a resource type downloaded by download all, to a
+// sub-directory of download-directory, or to a file if fileName is set.
+type downloadAllResource struct {
+	name     string
+	subDir   string
+	fileName string
+	skip     []string
+	download func(saveDir string) (int, error)
+}
+
+var downloadAllCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Download OPA policies, automation rules, app-groups, policy mappings, Kyverno policies and policies configuration.",
+	Long:  "Download OPA policies, automation rules, app-groups, policy mappings, Kyverno policies and policies configuration from Insights, in the directory layout read by push all.",
+	Example: `
+	# Download all configuration from Insights
+	insights-cli download all -d .
+
+	# Download with override, then confirm there is nothing to push
+	insights-cli download all -d . --override
+	insights-cli plan -d .`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := os.MkdirAll(downloadDir, 0755)
+		if err != nil {
+			logrus.Fatalf("unable to create directory %s: %v", downloadDir, err)
+		}
+		resources := downloadAllResources(client, configurationObject.Options.Organization)
+		numSkipped, numFailures := downloadAll(resources)
+		if numFailures > 0 && numFailures < len(resources) {
+			logrus.Fatalln("Download partially failed.")
+		}
+		if numFailures == len(resources) {
+			logrus.Fatalln("Download failed.")
+		}
+		if numSkipped > 0 {
+			logrus.Warnf("Skipped %d resource type(s) with existing local files, use --override to override local files", numSkipped)
+			return
+		}
+		logrus.Infoln("Download succeeded.")
+	},
+}
+
+// downloadAllResources returns the resource types downloaded by download all,
+// in the layout read by push all.
+func downloadAllResources(client *req.Client, org string) []downloadAllResource {
+	return []downloadAllResource{
+		{name: "OPA policies", subDir: downloadAllOPASubDir, skip: fixtureFilePatterns, download: func(saveDir string) (int, error) {
+			return opa.DownloadChecks(client, org, saveDir)
+		}},
+		{name: "automation rules", subDir: downloadAllRulesSubDir, download: func(saveDir string) (int, error) {
+			return rules.DownloadRules(client, org, saveDir)
+		}},
+		{name: "policies configuration", fileName: "settings.yaml", download: func(saveDir string) (int, error) {
+			return 1, policies.DownloadPolicies(client, org, saveDir)
+		}},
+		{name: "app-groups", subDir: downloadAllAppGroupsSubDir, download: func(saveDir string) (int, error) {
+			appGroups, err := appgroups.FetchAppGroups(client, org)
+			if err != nil {
+				return 0, err
+			}
+			return writeEntities(saveDir, appGroups)
+		}},
+		{name: "policy-mappings", subDir: downloadAllPolicyMappingsSubDir, download: func(saveDir string) (int, error) {
+			policyMappings, err := policymappings.FetchPolicyMappings(client, org)
+			if err != nil {
+				return 0, err
+			}
+			return writeEntities(saveDir, policyMappings)
+		}},
+		{name: "Kyverno policies", subDir: downloadAllKyvernoPoliciesSubDir, skip: fixtureFilePatterns, download: func(saveDir string) (int, error) {
+			kyvernoPolicies, err := kyverno.FetchKyvernoPolicies(client, org)
+			if err != nil {
+				return 0, err
+			}
+			return writeEntities(saveDir, kyvernoPolicies)
+		}},
+	}
+}
+
+// downloadAll downloads each resource type to download-directory, logging
+// the outcome of each, and returns the number skipped because they have local
+// files and the number that failed.
+func downloadAll(resources []downloadAllResource) (numSkipped, numFailures int) {
+	for _, r := range resources {
+		target, ok, err := prepareDownloadAllResource(r)
+		if err != nil {
+			logrus.Errorf("Unable to download %s: %v", r.name, err)
+			numFailures++
+			continue
+		}
+		if !ok {
+			// prepareDownloadAllResource has warned about the local files
+			numSkipped++
+			continue
+		}
+		c, err := r.download(filepath.Join(downloadDir, r.subDir))
+		if err != nil {
+			logrus.Errorf("Unable to download %s: %v", r.name, err)
+			numFailures++
+			continue
+		}
+		if r.fileName != "" {
+			logrus.Infof("Downloaded %s to %s", r.name, target)
+		} else {
+			logrus.Infof("Downloaded %d %s to %s", c, r.name, target)
+		}
+	}
+	return numSkipped, numFailures
+}
+
+// prepareDownloadAllResource prepares the file or sub-directory r is
+// downloaded to, returning its path and false if it has local files and
+// --override is not set.
+func prepareDownloadAllResource(r downloadAllResource) (string, bool, error) {
+	if r.fileName != "" {
+		target := filepath.Join(downloadDir, r.fileName)
+		ok, err := prepareDownloadFile(target, overrideLocalFiles)
+		return target, ok, err
+	}
+	target := filepath.Join(downloadDir, r.subDir)
+	err := os.MkdirAll(target, 0755)
+	if err != nil {
+		return target, false, err
+	}
+	ok, err := prepareDownloadDirectory(target, overrideLocalFiles, r.skip)
+	return target, ok, err
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+)
+
+// downloadAllResponses are the responses of a fake Insights API, by path.
+var downloadAllResponses = map[string]string{
+	"/v0/organizations/acme/opa/customChecks":                       `[{"Name": "needs-label", "Version": 2, "Rego": "package fairwinds\n"}]`,
+	"/v0/organizations/acme/opa/customChecks/needs-label/instances": `[{"CheckName": "needs-label", "Targets": ["apps/Deployment"], "AdditionalData": {"Name": "team", "Parameters": {"label": "team"}}}]`,
+	"/v0/organizations/acme/rules":                                  `[{"ID": 1, "Name": "severity", "Context": "Agent", "Action": "action.set('Severity', 0.9)"}]`,
+	"/v0/organizations/acme/policies":                               "checks:\n  polaris:\n    cpuLimitsMissing: warning\n",
+	"/v0/organizations/acme/app-groups":                             `[{"name": "web", "type": "AppGroup", "spec": {"match": [{"namespaces": ["web"]}]}}]`,
+	"/v0/organizations/acme/policy-mappings":                        `[]`,
+	"/v0/organizations/acme/kyverno-policies":                       `{"policies": [], "total": 0}`,
+}
+
+func TestDownloadAllRoundTrip(t *testing.T) {
+	var writes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes = append(writes, r.Method+" "+r.URL.Path)
+		}
+		body, found := downloadAllResponses[r.URL.Path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	client := req.C().SetBaseURL(server.URL)
+	downloadDir = t.TempDir()
+	defer func() { downloadDir = "" }()
+
+	resources := downloadAllResources(client, "acme")
+	numSkipped, numFailures := downloadAll(resources)
+	assert.Zero(t, numSkipped)
+	assert.Zero(t, numFailures)
+	for _, name := range []string{"opa/needs-label/policy.rego", "opa/needs-label/instances/team.yaml", "rules/severity.yaml", "settings.yaml", "app-groups/web.yaml"} {
+		assert.FileExists(t, filepath.Join(downloadDir, name))
+	}
+	assert.DirExists(t, filepath.Join(downloadDir, "policy-mappings"))
+	assert.DirExists(t, filepath.Join(downloadDir, "kyverno-policies"))
+
+	// pushing what was downloaded changes nothing
+	p, err := plan.Build(client, "acme", plan.Directories{
+		Base:            downloadDir,
+		OPA:             defaultPushOPASubDir,
+		Rules:           defaultPushRulesSubDir,
+		AppGroups:       defaultPushAppGroupsSubDir,
+		PolicyMappings:  defaultPushPolicyMappingsSubDir,
+		KyvernoPolicies: defaultPushKyvernoPoliciesSubDir,
+	}, true, "v0", state.New("https://insights.fairwinds.com", "acme"))
+	assert.NoError(t, err)
+	assert.False(t, plan.HasChanges(*p))
+	assert.Empty(t, writes)
+
+	// local files are kept without --override
+	assert.NoError(t, os.WriteFile(filepath.Join(downloadDir, "rules", "local.yaml"), []byte("name: local"), 0644))
+	numSkipped, numFailures = downloadAll(resources)
+	// the empty policy-mappings and Kyverno policies directories are not skipped
+	assert.Equal(t, len(resources)-2, numSkipped)
+	assert.Zero(t, numFailures)
+	assert.FileExists(t, filepath.Join(downloadDir, "rules", "local.yaml"))
+}
@@ -65,8 +65,8 @@ var downloadKyvernoPoliciesCmd = &cobra.Command{
 			logrus.Fatalf("unable to create directory %s: %v", saveDir, err)
 		}
 
-		// We do not want to delete files that contains .success.yaml and .failure.yaml.
-		c, err := saveEntitiesLocally(saveDir, kyvernoPolicies, overrideLocalFiles, []string{".success.yaml", ".failure.yaml"})
+		// We do not want to delete the validation test cases of policies.
+		c, err := saveEntitiesLocally(saveDir, kyvernoPolicies, overrideLocalFiles, fixtureFilePatterns)
 		if err != nil {
 			logrus.Fatalf("error saving kyverno-policies locally: %v", err)
 		}
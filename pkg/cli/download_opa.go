@@ -0,0 +1,64 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var downloadOPASubDir string
+
+func init() {
+	downloadOPACmd.PersistentFlags().StringVar(&downloadOPASubDir, "download-subdirectory", defaultPushOPASubDir, "Sub-directory within download-directory, to download OPA policies.")
+	downloadCmd.AddCommand(downloadOPACmd)
+}
+
+var downloadOPACmd = &cobra.Command{
+	Use:   "opa",
+	Short: "Download OPA policies from Insights to local files.",
+	Long:  "Download OPA policies and their instances from Insights to local files, in the directory layout read by push opa.",
+	Example: `
+	# Download all OPA policies from Insights
+	insights-cli download opa -d .
+
+	# Download with override, then confirm there is nothing to push
+	insights-cli download opa -d . --override
+	insights-cli push opa -d . --dry-run`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		org := configurationObject.Options.Organization
+		saveDir := downloadDir + "/" + downloadOPASubDir
+		err := os.MkdirAll(saveDir, 0755)
+		if err != nil {
+			logrus.Fatalf("unable to create directory %s: %v", saveDir, err)
+		}
+		ok, err := prepareDownloadDirectory(saveDir, overrideLocalFiles, fixtureFilePatterns)
+		if err != nil {
+			logrus.Fatalf("error preparing directory %s: %v", saveDir, err)
+		}
+		if !ok {
+			return
+		}
+		c, err := opa.DownloadChecks(client, org, saveDir)
+		if err != nil {
+			logrus.Fatalf("error saving OPA policies locally: %v", err)
+		}
+		logrus.Infof("Downloaded %d OPA policies from Insights to %s", c, saveDir)
+	},
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var downloadRulesSubDir string
+
+func init() {
+	downloadRulesCmd.PersistentFlags().StringVar(&downloadRulesSubDir, "download-subdirectory", defaultPushRulesSubDir, "Sub-directory within download-directory, to download automation rules.")
+	downloadCmd.AddCommand(downloadRulesCmd)
+}
+
+var downloadRulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Download automation rules from Insights to local files.",
+	Long:  "Download automation rules from Insights to local files. The settings of each rule are saved to a YAML file, and its action to a JavaScript file with the same name, which push rules reads back.",
+	Example: `
+	# Download all automation rules from Insights
+	insights-cli download rules -d .
+
+	# Download with override, then confirm there is nothing to push
+	insights-cli download rules -d . --override
+	insights-cli push rules -d . --dry-run`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		org := configurationObject.Options.Organization
+		saveDir := downloadDir + "/" + downloadRulesSubDir
+		err := os.MkdirAll(saveDir, 0755)
+		if err != nil {
+			logrus.Fatalf("unable to create directory %s: %v", saveDir, err)
+		}
+		ok, err := prepareDownloadDirectory(saveDir, overrideLocalFiles, []string{})
+		if err != nil {
+			logrus.Fatalf("error preparing directory %s: %v", saveDir, err)
+		}
+		if !ok {
+			return
+		}
+		c, err := rules.DownloadRules(client, org, saveDir)
+		if err != nil {
+			logrus.Fatalf("error saving automation rules locally: %v", err)
+		}
+		logrus.Infof("Downloaded %d automation rules from Insights to %s", c, saveDir)
+	},
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	downloadCmd.AddCommand(downloadSettingsCmd)
+}
+
+var downloadSettingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Download policies configuration from Insights to a local file.",
+	Long:  "Download policies configuration from Insights to a settings.yaml file in download-directory, which push settings reads back.",
+	Example: `
+	# Download policies configuration from Insights
+	insights-cli download settings -d .
+
+	# Download with override
+	insights-cli download settings -d . --override`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		org := configurationObject.Options.Organization
+		err := os.MkdirAll(downloadDir, 0755)
+		if err != nil {
+			logrus.Fatalf("unable to create directory %s: %v", downloadDir, err)
+		}
+		ok, err := prepareDownloadFile(downloadDir+"/settings.yaml", overrideLocalFiles)
+		if err != nil {
+			logrus.Fatalf("error preparing file %s/settings.yaml: %v", downloadDir, err)
+		}
+		if !ok {
+			return
+		}
+		err = policies.DownloadPolicies(client, org, downloadDir)
+		if err != nil {
+			logrus.Fatalf("error saving policies configuration locally: %v", err)
+		}
+		logrus.Infof("Downloaded policies configuration from Insights to %s/settings.yaml", downloadDir)
+	},
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/fairwindsops/insights-cli/pkg/teams"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	downloadCmd.AddCommand(downloadTeamsCmd)
+}
+
+var downloadTeamsCmd = &cobra.Command{
+	Use:   "teams",
+	Short: "Download teams configuration from Insights to a local file.",
+	Long:  "Download teams configuration from Insights to a teams.yaml file in download-directory, which push teams reads back.",
+	Example: `
+	# Download teams configuration from Insights
+	insights-cli download teams -d .
+
+	# Download with override, then confirm there is nothing to push
+	insights-cli download teams -d . --override
+	insights-cli plan -d .`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		org := configurationObject.Options.Organization
+		err := os.MkdirAll(downloadDir, 0755)
+		if err != nil {
+			logrus.Fatalf("unable to create directory %s: %v", downloadDir, err)
+		}
+		ok, err := prepareDownloadFile(downloadDir+"/teams.yaml", overrideLocalFiles)
+		if err != nil {
+			logrus.Fatalf("error preparing file %s/teams.yaml: %v", downloadDir, err)
+		}
+		if !ok {
+			return
+		}
+		c, err := teams.DownloadTeams(client, org, downloadDir)
+		if err != nil {
+			logrus.Fatalf("error saving teams configuration locally: %v", err)
+		}
+		logrus.Infof("Downloaded %d teams from Insights to %s/teams.yaml", c, downloadDir)
+	},
+}
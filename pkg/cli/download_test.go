@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeDirectoryKeepsFixtures(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"needs-label/policy.rego",
+		"needs-label/.policy-settings.yaml",
+		"needs-label/instances/team.yaml",
+		"needs-label/policy.pod.success.yaml",
+		"needs-label/policy.pod.failure.yaml",
+		"needs-label/policy.pod.expected.yaml",
+		"removed/policy.rego",
+	}
+	for _, name := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("content"), 0644))
+	}
+
+	ok, err := prepareDownloadDirectory(dir, true, fixtureFilePatterns)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	var remaining []string
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			remaining = append(remaining, rel)
+		}
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"needs-label/policy.pod.expected.yaml",
+		"needs-label/policy.pod.failure.yaml",
+		"needs-label/policy.pod.success.yaml",
+	}, remaining)
+	assert.NoDirExists(t, filepath.Join(dir, "removed"))
+}
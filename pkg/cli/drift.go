@@ -0,0 +1,90 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+)
+
+// driftExitCode is the exit status of the drift command when Insights has
+// drifted from the push directory. Other failures exit with status 1.
+const driftExitCode = 2
+
+func init() {
+	driftCmd.Flags().StringVarP(&pushDir, "push-directory", "d", ".", "Directory of content to compare with Insights.")
+	driftCmd.Flags().BoolVarP(&pushDelete, "delete", "D", false, "Also report resources in Insights that are not provided in the push directory.")
+	driftCmd.Flags().StringVarP(&pushRegoVersion, "rego-version", "v", "v0", "The version of Rego used to compile the OPA policies.")
+	driftCmd.Flags().StringVarP(&pushOPASubDir, "push-opa-subdirectory", "", defaultPushOPASubDir, "Sub-directory within push-directory, to contain OPA policies.")
+	driftCmd.Flags().StringVarP(&pushRulesSubDir, "push-rules-subdirectory", "", defaultPushRulesSubDir, "Sub-directory within push-directory, to contain automation rules.")
+	driftCmd.Flags().StringVarP(&pushAppGroupsSubDir, "push-app-groups-subdirectory", "", defaultPushAppGroupsSubDir, "Sub-directory within push-directory, to contain App Groups.")
+	driftCmd.Flags().StringVarP(&pushPolicyMappingsSubDir, "push-policy-mappings-subdirectory", "", defaultPushPolicyMappingsSubDir, "Sub-directory within push-directory, to contain Policy Mappings.")
+	driftCmd.Flags().StringVarP(&pushOverlay, "overlay", "", "", "Overlay within the "+overlay.DirName+" directory of the push directory, whose patches are applied to app-groups, policy-mappings and OPA policies.")
+	driftCmd.Flags().StringVarP(&pushValuesFile, "values", "", "", valuesFlagUsage)
+	driftCmd.Flags().StringVarP(&pushKyvernoPoliciesSubDir, "push-kyverno-policies-subdirectory", "", defaultPushKyvernoPoliciesSubDir, "Sub-directory within push-directory, to contain Kyverno policies.")
+	rootCmd.AddCommand(driftCmd)
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift -d <push directory>",
+	Short: "Detect drift between Insights and a push directory.",
+	Long:  "Compare every resource type in the push directory with Insights without changing anything, print the differences, and exit with status 2 if Insights has drifted from the push directory.",
+	Example: `
+	# Check whether Insights matches the push directory
+	insights-cli drift -d .
+
+	# Also report resources created in Insights that are not in the push directory
+	insights-cli drift -d . --delete`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, err := os.Stat(pushDir)
+		if err != nil {
+			logrus.Fatalf("Unable to check drift from Insights (%s): %v", pushDir, err)
+		}
+		err = setPushFileOptions()
+		if err != nil {
+			logrus.Fatalf("Unable to read push options: %v", err)
+		}
+		org := configurationObject.Options.Organization
+		stateFile, err := stateFileName(org)
+		if err != nil {
+			logrus.Fatalf("Unable to read the state file: %v", err)
+		}
+		st, err := state.Load(stateFile, configurationObject.Options.Hostname, org)
+		if err != nil {
+			logrus.Fatalf("Unable to read the state file %s: %v", stateFile, err)
+		}
+		p, err := plan.Build(client, org, pushDirectories(), pushDelete, pushRegoVersion, st)
+		if err != nil {
+			logrus.Fatalf("Unable to compare with Insights: %v", err)
+		}
+		if !plan.HasChanges(*p) {
+			logrus.Infoln("No drift detected, Insights matches the push directory.")
+			return
+		}
+		err = plan.Print(os.Stdout, *p)
+		if err != nil {
+			logrus.Fatalf("Unable to print drift: %v", err)
+		}
+		logrus.Warnln("Drift detected, Insights does not match the push directory.")
+		os.Exit(driftExitCode)
+	},
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+)
+
+var planOutputFile string
+
+func init() {
+	planCmd.Flags().StringVarP(&pushDir, "push-directory", "d", ".", "Directory of content to plan pushing to Insights.")
+	planCmd.Flags().StringVarP(&planOutputFile, "output", "o", "", "File to save the plan to, for use with the apply command.")
+	planCmd.Flags().BoolVarP(&pushDelete, "delete", "D", false, "Plan to delete resources that are not provided in the push directory. When an owner is set, only resources previously pushed with the same owner are deleted.")
+	planCmd.Flags().StringVarP(&pushRegoVersion, "rego-version", "v", "v0", "The version of Rego used to compile the OPA policies.")
+	planCmd.Flags().StringVarP(&pushOPASubDir, "push-opa-subdirectory", "", defaultPushOPASubDir, "Sub-directory within push-directory, to contain OPA policies.")
+	planCmd.Flags().StringVarP(&pushRulesSubDir, "push-rules-subdirectory", "", defaultPushRulesSubDir, "Sub-directory within push-directory, to contain automation rules.")
+	planCmd.Flags().StringVarP(&pushAppGroupsSubDir, "push-app-groups-subdirectory", "", defaultPushAppGroupsSubDir, "Sub-directory within push-directory, to contain App Groups.")
+	planCmd.Flags().StringVarP(&pushPolicyMappingsSubDir, "push-policy-mappings-subdirectory", "", defaultPushPolicyMappingsSubDir, "Sub-directory within push-directory, to contain Policy Mappings.")
+	planCmd.Flags().StringVarP(&pushOverlay, "overlay", "", "", "Overlay within the "+overlay.DirName+" directory of the push directory, whose patches are applied to app-groups, policy-mappings and OPA policies.")
+	planCmd.Flags().StringVarP(&pushValuesFile, "values", "", "", valuesFlagUsage)
+	planCmd.Flags().StringVarP(&pushKyvernoPoliciesSubDir, "push-kyverno-policies-subdirectory", "", defaultPushKyvernoPoliciesSubDir, "Sub-directory within push-directory, to contain Kyverno policies.")
+	rootCmd.AddCommand(planCmd)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan -d <push directory> -o <plan file>",
+	Short: "Plan the changes a push would make to Insights.",
+	Long:  "Compare every resource type in the push directory with Insights, and save the resulting changes to a plan file that can be reviewed and later executed with the apply command.",
+	Example: `
+	# Save a plan of the changes to push
+	insights-cli plan -d . -o plan.json
+
+	# Include deletion of resources that are not in the push directory
+	insights-cli plan -d . -o plan.json --delete`,
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, err := os.Stat(pushDir)
+		if err != nil {
+			logrus.Fatalf("Unable to plan push to Insights (%s): %v", pushDir, err)
+		}
+		err = setPushFileOptions()
+		if err != nil {
+			logrus.Fatalf("Unable to read push options: %v", err)
+		}
+		org := configurationObject.Options.Organization
+		stateFile, err := stateFileName(org)
+		if err != nil {
+			logrus.Fatalf("Unable to read the state file: %v", err)
+		}
+		st, err := state.Load(stateFile, configurationObject.Options.Hostname, org)
+		if err != nil {
+			logrus.Fatalf("Unable to read the state file %s: %v", stateFile, err)
+		}
+		p, err := plan.Build(client, org, pushDirectories(), pushDelete, pushRegoVersion, st)
+		if err != nil {
+			logrus.Fatalf("Unable to build plan: %v", err)
+		}
+		err = plan.Print(os.Stdout, *p)
+		if err != nil {
+			logrus.Fatalf("Unable to print plan: %v", err)
+		}
+		if planOutputFile == "" {
+			return
+		}
+		err = plan.Save(*p, planOutputFile)
+		if err != nil {
+			logrus.Fatalf("Unable to save plan to %s: %v", planOutputFile, err)
+		}
+		logrus.Infof("Plan saved to %s", planOutputFile)
+	},
+}
+
+// pushDirectories returns the location of each resource type, as set by the
+// push-directory and sub-directory flags.
+func pushDirectories() plan.Directories {
+	return plan.Directories{
+		Base:            pushDir,
+		OPA:             pushOPASubDir,
+		Rules:           pushRulesSubDir,
+		AppGroups:       pushAppGroupsSubDir,
+		PolicyMappings:  pushPolicyMappingsSubDir,
+		KyvernoPolicies: pushKyvernoPoliciesSubDir,
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/credentials"
+	cliversion "github.com/fairwindsops/insights-cli/pkg/version"
+)
+
+var profile string
+
+// baseOptions are the options of fairwinds-insights.yaml, before those of a
+// profile are applied.
+var baseOptions optionConfig
+
+// activeProfile is the name of the profile in use, if any.
+var activeProfile string
+
+// profileConfig overrides the options of fairwinds-insights.yaml, to push to
+// another organization or Insights instance.
+type profileConfig struct {
+	Hostname       string                 `yaml:"hostname"`
+	Organization   string                 `yaml:"organization"`
+	TokenEnv       string                 `yaml:"tokenEnv"`
+	Credentials    []credentials.Provider `yaml:"credentials"`
+	Subdirectories subdirectoryConfig     `yaml:"subdirectories"`
+	Values         map[string]string      `yaml:"values"`
+}
+
+// subdirectoryConfig overrides the default sub-directories of the push
+// directory. Sub-directories set by flags take precedence.
+type subdirectoryConfig struct {
+	OPA             string `yaml:"opa"`
+	Rules           string `yaml:"rules"`
+	AppGroups       string `yaml:"appGroups"`
+	PolicyMappings  string `yaml:"policyMappings"`
+	KyvernoPolicies string `yaml:"kyvernoPolicies"`
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "", "", "Profile in fairwinds-insights.yaml to use, which sets the hostname, organization, token and push sub-directories.")
+}
+
+// lookupProfile returns the named profile, or an empty profile if name is
+// empty.
+func lookupProfile(name string) (profileConfig, error) {
+	if name == "" {
+		return profileConfig{}, nil
+	}
+	p, ok := configurationObject.Profiles[name]
+	if !ok {
+		return p, fmt.Errorf("profile %s not found in %s", name, configFile)
+	}
+	return p, nil
+}
+
+// profileToken returns the Insights token of the named profile, from the
+// first of its credentials providers that has one. A profile without
+// providers uses its tokenEnv environment variable, or the credentials
+// providers of fairwinds-insights.yaml, or FAIRWINDS_TOKEN.
+func profileToken(name string) (string, error) {
+	p, err := lookupProfile(name)
+	if err != nil {
+		return "", err
+	}
+	if p.TokenEnv != "" && len(p.Credentials) > 0 {
+		return "", fmt.Errorf("profile %s cannot set both tokenEnv and credentials", name)
+	}
+	chain := p.Credentials
+	if p.TokenEnv != "" {
+		chain = []credentials.Provider{{Env: p.TokenEnv}}
+	}
+	if len(chain) == 0 {
+		chain = configurationObject.Credentials
+	}
+	return credentials.Token(chain)
+}
+
+// useProfile applies the options of the named profile, or the base options
+// if name is empty, and configures client to use them with token.
+func useProfile(client *req.Client, name, token string) error {
+	p, err := lookupProfile(name)
+	if err != nil {
+		return err
+	}
+	configurationObject.Options = baseOptions
+	if p.Hostname != "" {
+		configurationObject.Options.Hostname = p.Hostname
+	}
+	if p.Organization != "" {
+		configurationObject.Options.Organization = p.Organization
+	}
+	configurationObject.SetDefaults()
+	if organization != "" {
+		configurationObject.Options.Organization = organization
+	}
+	err = configurationObject.CheckForErrors()
+	if err != nil {
+		return fmt.Errorf("error parsing fairwinds-insights.yaml: %v", err)
+	}
+
+	// common client configuration
+	client.SetCommonHeaders(map[string]string{
+		"Authorization":           fmt.Sprintf("Bearer %s", token),
+		"X-Fairwinds-CLI-Version": cliversion.GetVersion(),
+	})
+	client.SetBaseURL(configurationObject.Options.Hostname)
+	activeProfile = name
+	return nil
+}
+
+// applyProfileSubdirectories sets the push sub-directory flags of cmd that
+// were not given to those of the active profile, or to their defaults.
+func applyProfileSubdirectories(cmd *cobra.Command) {
+	subdirs := configurationObject.Profiles[activeProfile].Subdirectories
+	for flagName, value := range map[string]string{
+		"push-opa-subdirectory":              subdirs.OPA,
+		"push-rules-subdirectory":            subdirs.Rules,
+		"push-app-groups-subdirectory":       subdirs.AppGroups,
+		"push-policy-mappings-subdirectory":  subdirs.PolicyMappings,
+		"push-kyverno-policies-subdirectory": subdirs.KyvernoPolicies,
+	} {
+		flag := cmd.Flags().Lookup(flagName)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if value == "" {
+			value = flag.DefValue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			logrus.Fatalf("Unable to set --%s: %v", flagName, err)
+		}
+	}
+}
+
+// runForProfiles returns a cobra Run function that runs run with the
+// selected profile, or with each profile in turn when --all-profiles is
+// given, stopping at the first profile that fails.
+func runForProfiles(run func(cmd *cobra.Command, args []string)) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if !pushAllProfiles {
+			applyProfileSubdirectories(cmd)
+			run(cmd, args)
+			return
+		}
+		if profile != "" || organization != "" {
+			logrus.Fatal("--all-profiles cannot be used with --profile or --organization")
+		}
+		names := lo.Keys(configurationObject.Profiles)
+		if len(names) == 0 {
+			logrus.Fatalf("--all-profiles requires profiles in %s", configFile)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			token, err := profileToken(name)
+			if err == nil {
+				err = useProfile(client, name, token)
+			}
+			if err != nil {
+				logrus.Fatalf("Unable to use profile %s: %v", name, err)
+			}
+			applyProfileSubdirectories(cmd)
+			logrus.Infof("Using profile %s, organization %s at %s", name, configurationObject.Options.Organization, configurationObject.Options.Hostname)
+			run(cmd, args)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// setProfiles replaces the configuration with the given base options and
+// profiles for the duration of the test.
+func setProfiles(t *testing.T, base optionConfig, profiles map[string]profileConfig) {
+	originalConfiguration, originalBase, originalClient := configurationObject, baseOptions, client
+	originalProfile, originalActive, originalOrganization := profile, activeProfile, organization
+	t.Cleanup(func() {
+		configurationObject, baseOptions, client = originalConfiguration, originalBase, originalClient
+		profile, activeProfile, organization = originalProfile, originalActive, originalOrganization
+	})
+	configurationObject = configuration{Options: base, Profiles: profiles}
+	baseOptions = base
+	client = req.C()
+	profile, activeProfile, organization = "", "", ""
+}
+
+func TestUseProfile(t *testing.T) {
+	setProfiles(t, optionConfig{Organization: "base-org"}, map[string]profileConfig{
+		"staging": {Organization: "staging-org", Hostname: "https://staging.example.com"},
+		"partial": {Hostname: "https://partial.example.com"},
+	})
+
+	assert.NoError(t, useProfile(client, "staging", "staging-token"))
+	assert.Equal(t, "staging", activeProfile)
+	assert.Equal(t, "staging-org", configurationObject.Options.Organization)
+	assert.Equal(t, "https://staging.example.com", client.BaseURL)
+	assert.Equal(t, "Bearer staging-token", client.Headers.Get("Authorization"))
+
+	assert.NoError(t, useProfile(client, "partial", "partial-token"))
+	assert.Equal(t, "base-org", configurationObject.Options.Organization, "options a profile does not set are those of the base")
+	assert.Equal(t, "https://partial.example.com", client.BaseURL)
+
+	assert.NoError(t, useProfile(client, "", "base-token"))
+	assert.Equal(t, "", activeProfile)
+	assert.Equal(t, "base-org", configurationObject.Options.Organization)
+	assert.Equal(t, "https://insights.fairwinds.com", client.BaseURL, "options of the previous profile are not kept")
+	assert.Equal(t, "Bearer base-token", client.Headers.Get("Authorization"))
+
+	organization = "flag-org"
+	assert.NoError(t, useProfile(client, "staging", "staging-token"))
+	assert.Equal(t, "flag-org", configurationObject.Options.Organization, "--organization takes precedence over the profile")
+
+	assert.ErrorContains(t, useProfile(client, "missing", "token"), "profile missing not found")
+}
+
+func TestUseProfileWithoutOrganization(t *testing.T) {
+	setProfiles(t, optionConfig{}, map[string]profileConfig{"staging": {}})
+	assert.ErrorContains(t, useProfile(client, "staging", "token"), "options.organization not set")
+}
+
+func TestApplyProfileSubdirectories(t *testing.T) {
+	setProfiles(t, optionConfig{}, map[string]profileConfig{
+		"staging": {Subdirectories: subdirectoryConfig{OPA: "staging-opa", Rules: "staging-rules"}},
+	})
+	cmd := &cobra.Command{}
+	opaDir := cmd.Flags().String("push-opa-subdirectory", "opa", "")
+	rulesDir := cmd.Flags().String("push-rules-subdirectory", "rules", "")
+	appGroupsDir := cmd.Flags().String("push-app-groups-subdirectory", "app-groups", "")
+	assert.NoError(t, cmd.Flags().Set("push-rules-subdirectory", "flag-rules"))
+
+	activeProfile = "staging"
+	applyProfileSubdirectories(cmd)
+	assert.Equal(t, "staging-opa", *opaDir, "the profile takes precedence over the default")
+	assert.Equal(t, "flag-rules", *rulesDir, "the flag takes precedence over the profile")
+	assert.Equal(t, "app-groups", *appGroupsDir, "the default is used when neither is set")
+
+	activeProfile = ""
+	applyProfileSubdirectories(cmd)
+	assert.Equal(t, "opa", *opaDir, "the default is restored for the next profile")
+	assert.Equal(t, "flag-rules", *rulesDir)
+}
+
+// errFatal is panicked by logrus.Fatal while exitOnFatal is in effect.
+type errFatal struct{}
+
+// exitOnFatal makes logrus.Fatal panic with errFatal, instead of exiting,
+// for the duration of the test.
+func exitOnFatal(t *testing.T) {
+	logger := logrus.StandardLogger()
+	originalExit := logger.ExitFunc
+	logger.ExitFunc = func(int) { panic(errFatal{}) }
+	t.Cleanup(func() { logger.ExitFunc = originalExit })
+}
+
+// runFatally runs run, and returns true if it called logrus.Fatal.
+func runFatally(run func()) (fatal bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(errFatal); !ok {
+				panic(r)
+			}
+			fatal = true
+		}
+	}()
+	run()
+	return false
+}
+
+func TestRunForProfiles(t *testing.T) {
+	setProfiles(t, optionConfig{}, map[string]profileConfig{
+		"staging": {Organization: "staging-org", TokenEnv: "STAGING_TOKEN"},
+		"dev":     {Organization: "dev-org", TokenEnv: "DEV_TOKEN"},
+		"prod":    {Organization: "prod-org", TokenEnv: "PROD_TOKEN"},
+	})
+	t.Setenv("STAGING_TOKEN", "staging-token")
+	t.Setenv("DEV_TOKEN", "dev-token")
+	t.Setenv("PROD_TOKEN", "prod-token")
+	pushAllProfiles = true
+	defer func() { pushAllProfiles = false }()
+	exitOnFatal(t)
+
+	var ran []string
+	run := runForProfiles(func(cmd *cobra.Command, args []string) {
+		ran = append(ran, activeProfile+"="+configurationObject.Options.Organization)
+	})
+	assert.False(t, runFatally(func() { run(&cobra.Command{}, nil) }))
+	assert.Equal(t, []string{"dev=dev-org", "prod=prod-org", "staging=staging-org"}, ran, "profiles are run in order of name")
+
+	ran = nil
+	run = runForProfiles(func(cmd *cobra.Command, args []string) {
+		ran = append(ran, activeProfile)
+		if activeProfile == "prod" {
+			logrus.Fatal("push failed")
+		}
+	})
+	assert.True(t, runFatally(func() { run(&cobra.Command{}, nil) }))
+	assert.Equal(t, []string{"dev", "prod"}, ran, "profiles after the one that fails are not run")
+
+	ran = nil
+	t.Setenv("PROD_TOKEN", "")
+	assert.True(t, runFatally(func() { run(&cobra.Command{}, nil) }))
+	assert.Equal(t, []string{"dev"}, ran, "a profile without a token fails before it is run")
+}
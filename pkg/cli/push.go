@@ -15,20 +15,43 @@
 package cli
 
 import (
+	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+	"github.com/fairwindsops/insights-cli/pkg/variables"
 )
 
 var pushDir string
 var pushDryRun bool
 var pushDelete bool
+var pushStateFile string
+var pushRefresh bool
+var pushOnly []string
+var pushExclude []string
+var pushSelector string
+var pushAllProfiles bool
+var pushOverlay string
+var pushValuesFile string
 
 func init() {
 	pushCmd.PersistentFlags().StringVarP(&pushDir, "push-directory", "d", ".", "Directory of content to push to Insights.")
 	pushCmd.PersistentFlags().BoolVarP(&pushDryRun, "dry-run", "z", false, "Explains what would be pushed to Insights, without making changes.")
-	pushCmd.PersistentFlags().BoolVarP(&pushDelete, "delete", "D", false, "Deletes resources that are not provided in the push directory.")
+	pushCmd.PersistentFlags().BoolVarP(&pushDelete, "delete", "D", false, "Deletes resources that are not provided in the push directory. When an owner is set, only resources previously pushed with the same owner are deleted.")
+	pushCmd.PersistentFlags().StringVarP(&pushStateFile, "state-file", "", "", "File recording what was last pushed, used to skip resources that have not changed since. Defaults to a file in the insights-cli/state directory of the user cache directory, named after the hostname, the organization and the absolute path of the push directory.")
+	pushCmd.PersistentFlags().BoolVarP(&pushRefresh, "refresh", "", false, "Compare every resource with Insights, even if it has not changed since the last push recorded in the state file.")
+	pushCmd.PersistentFlags().BoolVarP(&pushAllProfiles, "all-profiles", "", false, "Push with each profile in fairwinds-insights.yaml in turn, stopping at the first profile that fails.")
+	pushCmd.PersistentFlags().StringVarP(&pushOverlay, "overlay", "", "", "Overlay within the "+overlay.DirName+" directory of the push directory, whose patches are applied to app-groups, policy-mappings and OPA policies before pushing.")
+	pushCmd.PersistentFlags().StringVarP(&pushValuesFile, "values", "", "", valuesFlagUsage)
 	rootCmd.AddCommand(pushCmd)
 }
 
@@ -45,3 +68,153 @@ var pushCmd = &cobra.Command{
 		os.Exit(1)
 	},
 }
+
+// addPushFilterFlags adds the flags selecting which resources are pushed to
+// cmd, which are applied by validateConfigAndSetPushFilter.
+func addPushFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&pushOnly, "only", "", nil, "Only push resources whose name matches one of these glob patterns, such as team-*. Resources that are not pushed are never deleted.")
+	cmd.Flags().StringSliceVarP(&pushExclude, "exclude", "", nil, "Do not push resources whose name matches one of these glob patterns. Resources that are not pushed are never deleted.")
+	cmd.Flags().StringVarP(&pushSelector, "selector", "l", "", "Only push resources whose labels match this selector, such as team=platform,env!=prod. Labels are only read from local files, so resources only in Insights are not deleted while a selector is set.")
+}
+
+// validateConfigAndSetPushFilter is the PreRun of push commands that accept
+// the flags added by addPushFilterFlags.
+func validateConfigAndSetPushFilter(cmd *cobra.Command, args []string) {
+	validateAndLoadInsightsAPIConfigWrapper(cmd, args)
+	f, err := filter.New(pushOnly, pushExclude, pushSelector)
+	if err != nil {
+		logrus.Fatalf("Unable to filter resources to push: %v", err)
+	}
+	filter.Set(f)
+	if pushDelete && pushSelector != "" {
+		logrus.Warn("Resources only in Insights are not deleted when pushing with --selector, as their labels are only read from local files")
+	}
+}
+
+const valuesFlagUsage = "YAML file of values of the ${NAME} variables expanded in pushed YAML files. These override environment variables named INSIGHTS_VAR_ followed by the variable name, which override values in fairwinds-insights.yaml."
+
+// setPushFileOptions sets the values of variables expanded in pushed files,
+// and the overlay given by --overlay, if any.
+func setPushFileOptions() error {
+	values, err := variableValues()
+	if err != nil {
+		return err
+	}
+	variables.Set(values)
+	if pushOverlay == "" {
+		overlay.Set(overlay.Overlay{})
+		return nil
+	}
+	o, err := overlay.New(pushDir, pushOverlay)
+	if err != nil {
+		return err
+	}
+	overlay.Set(o)
+	return nil
+}
+
+// variableEnvPrefix is the prefix of environment variables that set the
+// values of variables, so that INSIGHTS_VAR_NAME sets ${NAME}.
+const variableEnvPrefix = "INSIGHTS_VAR_"
+
+// variableValues returns the values of variables in fairwinds-insights.yaml
+// and the active profile, overridden by environment variables prefixed with
+// variableEnvPrefix, overridden by the file given by --values.
+func variableValues() (map[string]string, error) {
+	values := map[string]string{}
+	maps.Copy(values, configurationObject.Values)
+	maps.Copy(values, configurationObject.Profiles[activeProfile].Values)
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if name, ok := strings.CutPrefix(name, variableEnvPrefix); ok && name != "" {
+			values[name] = value
+		}
+	}
+	if pushValuesFile != "" {
+		fileValues, err := variables.LoadFile(pushValuesFile)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(values, fileValues)
+	}
+	return values, nil
+}
+
+// pushWithState pushes resourceType from the push directory, skipping it if
+// it has not changed since the last push recorded in the state file. The
+// state file is updated unless this is a dry run.
+func pushWithState(org, resourceType string) error {
+	stateFile, err := stateFileName(org)
+	if err != nil {
+		return err
+	}
+	err = setPushFileOptions()
+	if err != nil {
+		return err
+	}
+	st, err := state.Load(stateFile, configurationObject.Options.Hostname, org)
+	if err != nil {
+		return err
+	}
+	err = plan.Push(client, org, pushDirectories(), resourceType, pushDelete, pushDryRun, pushRegoVersion, st, pushRefresh)
+	if pushDryRun {
+		return err
+	}
+	if saveErr := st.Save(stateFile); saveErr != nil {
+		logrus.Warnf("Unable to save state file %s: %v", stateFile, saveErr)
+	}
+	return err
+}
+
+// stateFileName returns the name of the state file recording pushes from the
+// push directory to org on the configured host, and excludes it from the hash
+// of pushed content.
+func stateFileName(org string) (string, error) {
+	stateFile := pushStateFile
+	if stateFile == "" {
+		dir, err := cacheDir("state")
+		if err != nil {
+			return "", fmt.Errorf("unable to locate the state directory: %w", err)
+		}
+		fileName, err := state.FileName(configurationObject.Options.Hostname, org, pushDir)
+		if err != nil {
+			return "", err
+		}
+		stateFile = filepath.Join(dir, fileName)
+	}
+	state.SkipFile(stateFile)
+	return stateFile, nil
+}
+
+// pendingResourceTypes returns the resource types pushed by push all that
+// have content in the push directory and have changed since the last push
+// recorded in the state file.
+func pendingResourceTypes(org string) ([]string, error) {
+	err := setPushFileOptions()
+	if err != nil {
+		return nil, err
+	}
+	stateFile, err := stateFileName(org)
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Load(stateFile, configurationObject.Options.Hostname, org)
+	if err != nil {
+		return nil, err
+	}
+	dirs := pushDirectories()
+	var pending []string
+	for _, resourceType := range pushAllResourceTypeNames {
+		if _, err := os.Stat(dirs.Path(resourceType)); err != nil {
+			continue
+		}
+		changed, err := plan.Pending(dirs, resourceType, pushDelete, pushRegoVersion, st, pushRefresh)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			pending = append(pending, resourceType)
+		}
+	}
+	return pending, nil
+}
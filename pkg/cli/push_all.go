@@ -15,21 +15,24 @@
 package cli
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/fairwindsops/insights-cli/pkg/appgroups"
-	"github.com/fairwindsops/insights-cli/pkg/kyverno"
-	"github.com/fairwindsops/insights-cli/pkg/opa"
-	"github.com/fairwindsops/insights-cli/pkg/policies"
-	"github.com/fairwindsops/insights-cli/pkg/policymappings"
-	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 )
 
+// pushAllResourceTypeNames are the resource types pushed by push all.
+var pushAllResourceTypeNames = []string{plan.ResourceOPA, plan.ResourceRules, plan.ResourceSettings, plan.ResourceAppGroups, plan.ResourcePolicyMappings, plan.ResourceKyvernoPolicies}
+
 var warningsAreFatal bool
+var pushSnapshotFile string
+var pushSkipSnapshot bool
 
 func init() {
 	pushAllCmd.PersistentFlags().StringVarP(&pushOPASubDir, "push-opa-subdirectory", "", defaultPushOPASubDir, "Sub-directory within push-directory, to contain OPA policies.")
@@ -38,6 +41,9 @@ func init() {
 	pushAllCmd.PersistentFlags().StringVarP(&pushPolicyMappingsSubDir, "push-policy-mappings-subdirectory", "", defaultPushPolicyMappingsSubDir, "Sub-directory within push-directory, to contain Policy Mappings.")
 	pushAllCmd.PersistentFlags().StringVarP(&pushKyvernoPoliciesSubDir, "push-kyverno-policies-subdirectory", "", defaultPushKyvernoPoliciesSubDir, "Sub-directory within push-directory, to contain Kyverno policies.")
 	pushAllCmd.PersistentFlags().BoolVarP(&warningsAreFatal, "warnings-are-fatal", "", false, "Treat warnings as a failure and exit with a non-zero status. For example, if pushing OPA policies and automation rules succeeds, but pushing policies configuration fails because the settings.yaml file is not present.")
+	pushAllCmd.PersistentFlags().StringVarP(&pushSnapshotFile, "snapshot-file", "", "", "File to save a snapshot of the current state of Insights to before pushing, for use with the rollback command. Defaults to insights-snapshot-<timestamp>.json, or insights-snapshot-<profile>-<timestamp>.json with a profile, in the insights-cli/snapshots directory of the user cache directory. With --all-profiles, the profile is added to the file name.")
+	pushAllCmd.PersistentFlags().BoolVarP(&pushSkipSnapshot, "no-snapshot", "", false, "Do not save a snapshot of the current state of Insights before pushing.")
+	addPushFilterFlags(pushAllCmd)
 	pushCmd.AddCommand(pushAllCmd)
 }
 
@@ -45,101 +51,21 @@ var pushAllCmd = &cobra.Command{
 	Use:    "all",
 	Short:  "Push OPA policies, automation rules, app-groups, policy mappings and policies configuration.",
 	Long:   "Push OPA policies, automation rules, app-groups, policy mappings and policies configuration to Insights.",
-	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	PreRun: validateConfigAndSetPushFilter,
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		_, err := os.Stat(pushDir)
 		if err != nil {
 			logrus.Fatalf("Unable to push to Insights (%s): %v", pushDir, err)
 		}
 
 		org := configurationObject.Options.Organization
-		const resourcesTypeToPush = 6
-
-		var numWarnings, numFailures int
-		logrus.Infoln("Pushing OPA policies, automation rules, and policies configuration to Insights.")
-		absPushOPADir := filepath.Join(pushDir, pushOPASubDir)
-		_, err = os.Stat(absPushOPADir)
-		if err != nil {
-			logrus.Warnf("Unable to start push OPA directory (%s): %v", absPushOPADir, err)
-			numWarnings++
-		} else {
-			err = opa.PushOPAChecks(client, absPushOPADir, org, pushDelete, pushDryRun, pushRegoVersion)
-			if err != nil {
-				logrus.Errorf("Unable to push OPA policies: %v", err)
-				numFailures++
-			}
-		}
-
-		absPushRulesDir := filepath.Join(pushDir, pushRulesSubDir)
-		_, err = os.Stat(absPushRulesDir)
-		if err != nil {
-			logrus.Warnf("Unable to push automation rules (%s): %v", absPushRulesDir, err)
-			numWarnings++
-		} else {
-			err = rules.PushRules(client, absPushRulesDir, org, pushDelete, pushDryRun)
-			if err != nil {
-				logrus.Errorf("Unable to push automation rules: %v", err)
-				numFailures++
-			}
-		}
-
-		absPushPoliciesConfigFile := filepath.Join(pushDir, "settings.yaml")
-		_, err = os.Stat(absPushPoliciesConfigFile)
-		if err != nil {
-			logrus.Warnf("Unable to push policies configuration (%s): %v", absPushPoliciesConfigFile, err)
-			numWarnings++
-		} else {
-			err = policies.PushPolicies(client, pushDir, org, pushDryRun)
-			if err != nil {
-				logrus.Errorf("Unable to push policies configuration: %v", err)
-				numFailures++
-			}
-		}
+		resourcesTypeToPush := len(pushAllResourceTypeNames)
 
-		absPushAppGroupsDir := filepath.Join(pushDir, pushAppGroupsSubDir)
-		_, err = os.Stat(absPushAppGroupsDir)
+		snapshotFile, err := snapshotBeforePush(org)
 		if err != nil {
-			logrus.Warnf("Unable to push app-groups (%s): %v", absPushAppGroupsDir, err)
-			numWarnings++
-		} else {
-			err = appgroups.PushAppGroups(client, absPushAppGroupsDir, org, pushDelete, pushDryRun)
-			if err != nil {
-				logrus.Errorf("Unable to push app-groups: %v", err)
-				numFailures++
-			}
-		}
-
-		absPushPolicyMappingsDir := filepath.Join(pushDir, pushPolicyMappingsSubDir)
-		_, err = os.Stat(absPushPolicyMappingsDir)
-		if err != nil {
-			logrus.Warnf("Unable to push policy-mappings (%s): %v", absPushPolicyMappingsDir, err)
-			numWarnings++
-		} else {
-			err = policymappings.PushPolicyMappings(client, absPushPolicyMappingsDir, org, pushDelete, pushDryRun)
-			if err != nil {
-				logrus.Errorf("Unable to push policy-mappings: %v", err)
-				numFailures++
-			}
-		}
-
-		absPushKyvernoPoliciesDir := filepath.Join(pushDir, pushKyvernoPoliciesSubDir)
-		_, err = os.Stat(absPushKyvernoPoliciesDir)
-		if err != nil {
-			logrus.Warnf("Unable to push Kyverno policies (%s): %v", absPushKyvernoPoliciesDir, err)
-			numWarnings++
-		} else {
-			policies, err := kyverno.GetPolicyFilesForPush(absPushKyvernoPoliciesDir)
-			if err != nil {
-				logrus.Errorf("Unable to read Kyverno policy files: %v", err)
-				numFailures++
-			} else {
-				err = kyverno.PushKyvernoPolicies(client, policies, org, pushDelete, pushDryRun)
-				if err != nil {
-					logrus.Errorf("Unable to push Kyverno policies: %v", err)
-					numFailures++
-				}
-			}
+			logrus.Fatalf("Unable to snapshot Insights before pushing: %v", err)
 		}
+		numWarnings, numFailures := pushAllResourceTypes(org)
 
 		if numFailures == 0 && numWarnings == 0 {
 			logrus.Infoln("Push succeeded.")
@@ -155,6 +81,10 @@ var pushAllCmd = &cobra.Command{
 			numFailures += numWarnings
 		}
 
+		if numFailures > 0 && snapshotFile != "" {
+			logrus.Warnf("To restore the state of Insights from before this push, run: insights-cli rollback %s", snapshotFile)
+		}
+
 		if numFailures > 0 && numFailures < resourcesTypeToPush {
 			logrus.Fatalln("Push partially failed.")
 		}
@@ -162,5 +92,159 @@ var pushAllCmd = &cobra.Command{
 		if numFailures == resourcesTypeToPush {
 			logrus.Fatalln("Push failed.")
 		}
-	},
+	}),
+}
+
+// snapshotBeforePush saves a snapshot of the resource types that push all
+// will push, unless this is a dry run or --no-snapshot is set, and returns
+// the name of the snapshot file. No snapshot is taken, and an empty name is
+// returned, when every resource type is unchanged since the last push.
+func snapshotBeforePush(org string) (string, error) {
+	if pushDryRun || pushSkipSnapshot {
+		return "", nil
+	}
+	resourceTypes, err := pendingResourceTypes(org)
+	if err != nil {
+		return "", err
+	}
+	if len(resourceTypes) == 0 {
+		logrus.Infoln("Not saving a snapshot of Insights, as nothing has changed since the last push.")
+		return "", nil
+	}
+	snapshotFile := pushSnapshotFile
+	if snapshotFile == "" {
+		snapshotFile, err = defaultSnapshotFile()
+		if err != nil {
+			return "", fmt.Errorf("unable to locate the snapshot directory: %w", err)
+		}
+	} else if pushAllProfiles {
+		ext := filepath.Ext(snapshotFile)
+		snapshotFile = strings.TrimSuffix(snapshotFile, ext) + "-" + activeProfile + ext
+	}
+	snapshot, err := plan.TakeSnapshot(client, org, pushDirectories(), resourceTypes)
+	if err != nil {
+		return "", err
+	}
+	err = plan.SaveSnapshot(*snapshot, snapshotFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to save snapshot to %s: %w", snapshotFile, err)
+	}
+	logrus.Infof("Saved a snapshot of Insights to %s", snapshotFile)
+	return snapshotFile, nil
+}
+
+// pushAllResourceTypes pushes each resource type in the push directory,
+// returning the number of resource types that were not found and the number
+// that failed to push.
+func pushAllResourceTypes(org string) (numWarnings, numFailures int) {
+	logrus.Infoln("Pushing OPA policies, automation rules, and policies configuration to Insights.")
+	absPushOPADir := filepath.Join(pushDir, pushOPASubDir)
+	_, err := os.Stat(absPushOPADir)
+	if err != nil {
+		logrus.Warnf("Unable to start push OPA directory (%s): %v", absPushOPADir, err)
+		numWarnings++
+	} else {
+		err = pushWithState(org, plan.ResourceOPA)
+		if err != nil {
+			logrus.Errorf("Unable to push OPA policies: %v", err)
+			numFailures++
+		}
+	}
+
+	absPushRulesDir := filepath.Join(pushDir, pushRulesSubDir)
+	_, err = os.Stat(absPushRulesDir)
+	if err != nil {
+		logrus.Warnf("Unable to push automation rules (%s): %v", absPushRulesDir, err)
+		numWarnings++
+	} else {
+		err = pushWithState(org, plan.ResourceRules)
+		if err != nil {
+			logrus.Errorf("Unable to push automation rules: %v", err)
+			numFailures++
+		}
+	}
+
+	absPushPoliciesConfigFile := filepath.Join(pushDir, "settings.yaml")
+	_, err = os.Stat(absPushPoliciesConfigFile)
+	if err != nil {
+		logrus.Warnf("Unable to push policies configuration (%s): %v", absPushPoliciesConfigFile, err)
+		numWarnings++
+	} else {
+		err = pushWithState(org, plan.ResourceSettings)
+		if err != nil {
+			logrus.Errorf("Unable to push policies configuration: %v", err)
+			numFailures++
+		}
+	}
+
+	absPushAppGroupsDir := filepath.Join(pushDir, pushAppGroupsSubDir)
+	_, err = os.Stat(absPushAppGroupsDir)
+	if err != nil {
+		logrus.Warnf("Unable to push app-groups (%s): %v", absPushAppGroupsDir, err)
+		numWarnings++
+	} else {
+		err = pushWithState(org, plan.ResourceAppGroups)
+		if err != nil {
+			logrus.Errorf("Unable to push app-groups: %v", err)
+			numFailures++
+		}
+	}
+
+	absPushPolicyMappingsDir := filepath.Join(pushDir, pushPolicyMappingsSubDir)
+	_, err = os.Stat(absPushPolicyMappingsDir)
+	if err != nil {
+		logrus.Warnf("Unable to push policy-mappings (%s): %v", absPushPolicyMappingsDir, err)
+		numWarnings++
+	} else {
+		err = pushWithState(org, plan.ResourcePolicyMappings)
+		if err != nil {
+			logrus.Errorf("Unable to push policy-mappings: %v", err)
+			numFailures++
+		}
+	}
+
+	absPushKyvernoPoliciesDir := filepath.Join(pushDir, pushKyvernoPoliciesSubDir)
+	_, err = os.Stat(absPushKyvernoPoliciesDir)
+	if err != nil {
+		logrus.Warnf("Unable to push Kyverno policies (%s): %v", absPushKyvernoPoliciesDir, err)
+		numWarnings++
+	} else {
+		err = pushWithState(org, plan.ResourceKyvernoPolicies)
+		if err != nil {
+			logrus.Errorf("Unable to push Kyverno policies: %v", err)
+			numFailures++
+		}
+	}
+	return numWarnings, numFailures
+}
+
+// defaultSnapshotFile returns the name of the snapshot file saved by push all
+// when --snapshot-file is not given, creating its directory.
+func defaultSnapshotFile() (string, error) {
+	dir, err := cacheDir("snapshots")
+	if err != nil {
+		return "", err
+	}
+	timestamp := time.Now().UTC().Format("20060102T150405Z")
+	if activeProfile != "" {
+		return filepath.Join(dir, fmt.Sprintf("insights-snapshot-%s-%s.json", activeProfile, timestamp)), nil
+	}
+	return filepath.Join(dir, fmt.Sprintf("insights-snapshot-%s.json", timestamp)), nil
+}
+
+// cacheDir returns the named insights-cli directory within the user cache
+// directory, or the temporary directory if there is no user cache directory,
+// creating it if needed.
+func cacheDir(name string) (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		logrus.Debugf("Using the temporary directory, as there is no user cache directory: %v", err)
+		userCacheDir = os.TempDir()
+	}
+	dir := filepath.Join(userCacheDir, "insights-cli", name)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
 }
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushAllUnchangedMakesNoRequests(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	originalClient := client
+	client = req.C().SetBaseURL(server.URL)
+	pushDir = t.TempDir()
+	pushStateFile = filepath.Join(t.TempDir(), "state.json")
+	pushSnapshotFile = filepath.Join(t.TempDir(), "snapshot.json")
+	defer func() {
+		client = originalClient
+		pushDir, pushStateFile, pushSnapshotFile = ".", "", ""
+	}()
+	assert.NoError(t, os.MkdirAll(filepath.Join(pushDir, pushRulesSubDir), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(pushDir, pushRulesSubDir, "severity.yaml"),
+		[]byte("name: severity\ncontext: Agent\naction: action.set('Severity', 0.9)\n"), 0644))
+
+	snapshotFile, err := snapshotBeforePush("acme")
+	assert.NoError(t, err)
+	assert.Equal(t, pushSnapshotFile, snapshotFile)
+	_, numFailures := pushAllResourceTypes("acme")
+	assert.Zero(t, numFailures)
+	assert.Contains(t, requests, "POST /v0/organizations/acme/rules/create")
+	assert.NoError(t, os.Remove(pushSnapshotFile))
+
+	requests = nil
+	snapshotFile, err = snapshotBeforePush("acme")
+	assert.NoError(t, err)
+	assert.Empty(t, snapshotFile, "nothing has changed, so no snapshot is saved")
+	_, numFailures = pushAllResourceTypes("acme")
+	assert.Zero(t, numFailures)
+	assert.Empty(t, requests, strings.Join(requests, ", "))
+	assert.NoFileExists(t, pushSnapshotFile)
+}
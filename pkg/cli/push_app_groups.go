@@ -15,7 +15,7 @@
 package cli
 
 import (
-	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +27,7 @@ const defaultPushAppGroupsSubDir = "app-groups"
 func init() {
 	// This flag sets a variable defined in the parent `push` command.
 	pushAppGroupsCmd.PersistentFlags().StringVarP(&pushAppGroupsSubDir, "push-app-groups-subdirectory", "", defaultPushAppGroupsSubDir, "Sub-directory within push-directory, to contain app-groups.")
+	addPushFilterFlags(pushAppGroupsCmd)
 	pushCmd.AddCommand(pushAppGroupsCmd)
 }
 
@@ -34,13 +35,13 @@ var pushAppGroupsCmd = &cobra.Command{
 	Use:    "app-groups",
 	Short:  "Push app-groups.",
 	Long:   "Push app-groups to Fairwinds Insights.",
-	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	PreRun: validateConfigAndSetPushFilter,
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := appgroups.PushAppGroups(client, pushDir+"/"+pushAppGroupsSubDir, org, pushDelete, pushDryRun)
+		err := pushWithState(org, plan.ResourceAppGroups)
 		if err != nil {
 			logrus.Fatalf("Unable to push app-groups: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
@@ -44,7 +44,7 @@ var pushExternalOPACmd = &cobra.Command{
 	Short:  "Push External OPA policies.",
 	Long:   "Push External OPA policies to Insights.",
 	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
 		filePath := fmt.Sprintf("%s/%s/%s", pushDir, pushExternalOPASubDir, pushExternalOPAFile)
 		err := opa.PushExternalOPAChecks(client, filePath, org, pushExternalOPAHeaders, pushDelete, pushDryRun, pushExternalRegoVersion)
@@ -52,5 +52,5 @@ var pushExternalOPACmd = &cobra.Command{
 			logrus.Fatalf("Unable to push external OPA checks: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
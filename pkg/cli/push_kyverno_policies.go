@@ -17,7 +17,10 @@ package cli
 import (
 	"os"
 
+	"github.com/fairwindsops/insights-cli/pkg/filter"
 	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -56,7 +59,7 @@ var pushKyvernoPoliciesCmd = &cobra.Command{
 	# Force push even if validation fails (use with extreme caution)
 	insights-cli push kyverno-policies --force`,
 	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
 
 		// Check if the push directory exists
@@ -65,6 +68,11 @@ var pushKyvernoPoliciesCmd = &cobra.Command{
 			logrus.Fatalf("Push directory %s does not exist. You need to create it.", pushDir)
 		}
 
+		err = setPushFileOptions()
+		if err != nil {
+			logrus.Fatalf("Unable to read push options: %v", err)
+		}
+
 		policyDir := pushDir + "/" + pushKyvernoPoliciesSubDir
 
 		// Check if the policy directory to push exists
@@ -79,48 +87,25 @@ var pushKyvernoPoliciesCmd = &cobra.Command{
 			logrus.Fatalf("Unable to read policy files: %v", err)
 		}
 
-		// Filter policies if specific ones are requested
-		var policiesToPush []kyverno.KyvernoPolicy
-		if len(pushSpecificPolicies) > 0 {
-			for _, requestedPolicy := range pushSpecificPolicies {
-				found := false
-				for _, policy := range allPolicies {
-					if policy.Name == requestedPolicy {
-						policiesToPush = append(policiesToPush, policy)
-						found = true
-						break
-					}
-				}
-				if !found {
-					logrus.Warnf("Policy '%s' not found in directory", requestedPolicy)
-				}
+		// Warn about specific policies that are requested but not found
+		for _, requestedPolicy := range pushSpecificPolicies {
+			if !lo.ContainsBy(allPolicies, func(p kyverno.KyvernoPolicy) bool { return p.Name == requestedPolicy }) {
+				logrus.Warnf("Policy '%s' not found in directory", requestedPolicy)
 			}
-		} else {
-			policiesToPush = allPolicies
-		}
-
-		if len(policiesToPush) == 0 {
-			logrus.Info("No policies to push")
-			return
 		}
 
-		if pushDryRun {
-			logrus.Infof("Dry run: Would synchronize %d Kyverno policies with Insights:", len(policiesToPush))
-			for _, policy := range policiesToPush {
-				logrus.Infof("  - %s (%s)", policy.Name, policy.Kind)
-			}
-			if pushDelete {
-				logrus.Info("Dry run: Would delete policies that exist in Insights but not locally")
-			}
-			return
+		// Only push the specific policies, if any are requested
+		f, err := filter.New(pushSpecificPolicies, nil, "")
+		if err != nil {
+			logrus.Fatalf("Unable to select the policies to push: %v", err)
 		}
+		filter.Set(f)
 
-		// Push to Insights
-		err = kyverno.PushKyvernoPolicies(client, policiesToPush, org, pushDelete, pushDryRun)
+		err = pushWithState(org, plan.ResourceKyvernoPolicies)
 		if err != nil {
 			logrus.Fatalf("Unable to synchronize kyverno-policies with Insights: %v", err)
 		}
 
 		logrus.Infoln("Successfully synchronized kyverno-policies with Insights.")
-	},
+	}),
 }
@@ -18,7 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 )
 
 var pushOPASubDir string
@@ -30,6 +30,7 @@ func init() {
 	// This flag sets a variable defined in the parent `push` command.
 	pushOPACmd.PersistentFlags().StringVarP(&pushOPASubDir, "push-opa-subdirectory", "", defaultPushOPASubDir, "Sub-directory within push-directory, to contain OPA policies.")
 	pushOPACmd.PersistentFlags().StringVarP(&pushRegoVersion, "rego-version", "v", "v0", "The version of Rego used to compile the policies.")
+	addPushFilterFlags(pushOPACmd)
 	pushCmd.AddCommand(pushOPACmd)
 }
 
@@ -37,13 +38,13 @@ var pushOPACmd = &cobra.Command{
 	Use:    "opa",
 	Short:  "Push OPA policies.",
 	Long:   "Push OPA policies to Insights.",
-	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	PreRun: validateConfigAndSetPushFilter,
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := opa.PushOPAChecks(client, pushDir+"/"+pushOPASubDir, org, pushDelete, pushDryRun, pushRegoVersion)
+		err := pushWithState(org, plan.ResourceOPA)
 		if err != nil {
 			logrus.Fatalf("Unable to push OPA Checks: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
@@ -15,7 +15,7 @@
 package cli
 
 import (
-	"github.com/fairwindsops/insights-cli/pkg/policymappings"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +26,7 @@ var defaultPushPolicyMappingsSubDir = "policy-mappings"
 func init() {
 	// This flag sets a variable defined in the parent `push` command.
 	pushPolicyMappingsCmd.PersistentFlags().StringVarP(&pushPolicyMappingsSubDir, "push-policy-mappings-subdirectory", "", defaultPushPolicyMappingsSubDir, "Sub-directory within push-directory, to contain policy-mappings.")
+	addPushFilterFlags(pushPolicyMappingsCmd)
 	pushCmd.AddCommand(pushPolicyMappingsCmd)
 }
 
@@ -33,13 +34,13 @@ var pushPolicyMappingsCmd = &cobra.Command{
 	Use:    "policy-mappings",
 	Short:  "Push policy-mappings.",
 	Long:   "Push policy-mappings to Fairwinds Insights.",
-	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	PreRun: validateConfigAndSetPushFilter,
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := policymappings.PushPolicyMappings(client, pushDir+"/"+pushPolicyMappingsSubDir, org, pushDelete, pushDryRun)
+		err := pushWithState(org, plan.ResourcePolicyMappings)
 		if err != nil {
 			logrus.Fatalf("Unable to push policy-mappings: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
@@ -18,7 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 )
 
 var pushRulesSubDir string
@@ -28,6 +28,7 @@ const defaultPushRulesSubDir = "rules"
 func init() {
 	// This flag sets a variable defined in the parent `push` command.
 	pushRulesCmd.PersistentFlags().StringVarP(&pushRulesSubDir, "push-rules-subdirectory", "", defaultPushRulesSubDir, "Sub-directory within push-directory, to contain automation rules.")
+	addPushFilterFlags(pushRulesCmd)
 	pushCmd.AddCommand(pushRulesCmd)
 }
 
@@ -35,13 +36,13 @@ var pushRulesCmd = &cobra.Command{
 	Use:    "rules",
 	Short:  "Push automation rules.",
 	Long:   "Push automation rules to Insights.",
-	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	PreRun: validateConfigAndSetPushFilter,
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := rules.PushRules(client, pushDir+"/"+pushRulesSubDir, org, pushDelete, pushDryRun)
+		err := pushWithState(org, plan.ResourceRules)
 		if err != nil {
 			logrus.Fatalf("Unable to push rules: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
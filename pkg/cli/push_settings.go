@@ -18,7 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 )
 
 func init() {
@@ -30,12 +30,12 @@ var pushSettingsCmd = &cobra.Command{
 	Short:  "Push policies configuration.",
 	Long:   "Push policies configuration to Insights to streamline settings across multiple Insights plugins.",
 	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := policies.PushPolicies(client, pushDir, org, pushDryRun)
+		err := pushWithState(org, plan.ResourceSettings)
 		if err != nil {
 			logrus.Fatalf("Unable to push policies configuration: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
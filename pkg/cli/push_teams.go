@@ -15,7 +15,7 @@
 package cli
 
 import (
-	"github.com/fairwindsops/insights-cli/pkg/teams"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -29,12 +29,12 @@ var pushTeamsCmd = &cobra.Command{
 	Short:  "Push teams configuration.",
 	Long:   "Push teams configuration to Insights for user permissions management.",
 	PreRun: validateAndLoadInsightsAPIConfigWrapper,
-	Run: func(cmd *cobra.Command, args []string) {
+	Run: runForProfiles(func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := teams.PushTeams(client, pushDir, org, pushDelete, pushDryRun)
+		err := pushWithState(org, plan.ResourceTeams)
 		if err != nil {
 			logrus.Fatalf("Unable to push teams configuration: %v", err)
 		}
 		logrus.Infoln("Push succeeded.")
-	},
+	}),
 }
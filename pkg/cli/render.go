@@ -0,0 +1,146 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/directory"
+	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+	"github.com/fairwindsops/insights-cli/pkg/variables"
+)
+
+func init() {
+	renderCmd.Flags().StringVarP(&pushDir, "push-directory", "d", ".", "Directory of content to render.")
+	renderCmd.Flags().StringVarP(&pushOPASubDir, "push-opa-subdirectory", "", defaultPushOPASubDir, "Sub-directory within push-directory, to contain OPA policies.")
+	renderCmd.Flags().StringVarP(&pushRulesSubDir, "push-rules-subdirectory", "", defaultPushRulesSubDir, "Sub-directory within push-directory, to contain automation rules.")
+	renderCmd.Flags().StringVarP(&pushAppGroupsSubDir, "push-app-groups-subdirectory", "", defaultPushAppGroupsSubDir, "Sub-directory within push-directory, to contain App Groups.")
+	renderCmd.Flags().StringVarP(&pushPolicyMappingsSubDir, "push-policy-mappings-subdirectory", "", defaultPushPolicyMappingsSubDir, "Sub-directory within push-directory, to contain Policy Mappings.")
+	renderCmd.Flags().StringVarP(&pushKyvernoPoliciesSubDir, "push-kyverno-policies-subdirectory", "", defaultPushKyvernoPoliciesSubDir, "Sub-directory within push-directory, to contain Kyverno policies.")
+	renderCmd.Flags().StringVarP(&pushOverlay, "overlay", "", "", "Overlay within the "+overlay.DirName+" directory of the push directory, whose patches are applied to app-groups, policy-mappings and OPA policies.")
+	renderCmd.Flags().StringVarP(&pushValuesFile, "values", "", "", valuesFlagUsage)
+	rootCmd.AddCommand(renderCmd)
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render -d <push directory>",
+	Short: "Print the files a push would send to Insights.",
+	Long:  "Print each file in the push directory as it would be pushed to Insights, with ${NAME} variables expanded in YAML files and any overlay applied. No Insights token is needed.",
+	Example: `
+	# Review the files that would be pushed
+	insights-cli render -d .
+
+	# Review the files of the production overlay, with values from a file
+	insights-cli render -d . --overlay production --values production-values.yaml`,
+	PreRun: loadConfigurationWithoutToken,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, err := os.Stat(pushDir)
+		if err != nil {
+			logrus.Fatalf("Unable to render push directory (%s): %v", pushDir, err)
+		}
+		applyProfileSubdirectories(cmd)
+		err = setPushFileOptions()
+		if err != nil {
+			logrus.Fatalf("Unable to read push options: %v", err)
+		}
+		err = render(os.Stdout, pushDirectories())
+		if err != nil {
+			logrus.Fatalf("Unable to render push directory: %v", err)
+		}
+	},
+}
+
+// loadConfigurationWithoutToken reads fairwinds-insights.yaml, if it exists,
+// and selects the profile given by --profile, for commands that do not call
+// the Insights API.
+func loadConfigurationWithoutToken(cmd *cobra.Command, args []string) {
+	_, err := readConfigurationFile()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	_, err = lookupProfile(profile)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	activeProfile = profile
+}
+
+// render writes the content of each file of dirs to w, as it would be
+// pushed, preceded by a comment naming the file.
+func render(w io.Writer, dirs plan.Directories) error {
+	for _, resourceType := range plan.ResourceTypes {
+		paths, read, err := renderedFiles(dirs.Path(resourceType), resourceType)
+		if err != nil {
+			return fmt.Errorf("error finding %s files: %w", resourceType, err)
+		}
+		slices.Sort(paths)
+		for _, path := range paths {
+			content, err := read(path)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", path, err)
+			}
+			fmt.Fprintf(w, "# Source: %s\n%s", path, content)
+			if len(content) > 0 && content[len(content)-1] != '\n' {
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	return nil
+}
+
+// renderedFiles returns the files of resourceType that are pushed from
+// folder, and the function that reads them as they are pushed.
+func renderedFiles(folder, resourceType string) ([]string, func(string) ([]byte, error), error) {
+	if _, err := os.Stat(folder); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	var files map[string][]string
+	var err error
+	switch resourceType {
+	case plan.ResourceOPA:
+		files, err = overlay.ScanFolder(folder, directory.ScanOPAFolder)
+		return flatten(files), overlay.ReadFile, err
+	case plan.ResourceAppGroups, plan.ResourcePolicyMappings:
+		files, err = overlay.ScanFolder(folder, directory.ScanFolder)
+		return flatten(files), overlay.ReadFile, err
+	case plan.ResourceRules:
+		files, err = directory.ScanFolder(folder)
+		return flatten(files), variables.ReadFile, err
+	case plan.ResourceKyvernoPolicies:
+		paths, err := kyverno.GetPolicyFilePathsForPush(folder)
+		return paths, variables.ReadFile, err
+	case plan.ResourceSettings, plan.ResourceTeams:
+		return []string{folder}, variables.ReadFile, nil
+	}
+	return nil, nil, nil
+}
+
+// flatten returns the file paths of files, which is a map of folders to
+// their files as returned by the directory scan functions.
+func flatten(files map[string][]string) []string {
+	var paths []string
+	for _, folderFiles := range files {
+		paths = append(paths, folderFiles...)
+	}
+	return paths
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/plan"
+)
+
+var rollbackDryRun bool
+var rollbackRegoVersion string
+
+func init() {
+	rollbackCmd.Flags().BoolVarP(&rollbackDryRun, "dry-run", "z", false, "Explains what would be restored in Insights, without making changes.")
+	rollbackCmd.Flags().StringVarP(&rollbackRegoVersion, "rego-version", "v", "v0", "The version of Rego used to compile restored OPA policies, when the snapshot does not record it.")
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <snapshot file>",
+	Short: "Restore Insights to a snapshot taken by push all.",
+	Long:  "Restore the resource types captured in a snapshot, which push all saves before making changes. Resources created since the snapshot was taken are deleted.",
+	Example: `
+	insights-cli rollback ~/.cache/insights-cli/snapshots/insights-snapshot-20260102T150405Z.json
+
+	# See what would be restored without making changes
+	insights-cli rollback ~/.cache/insights-cli/snapshots/insights-snapshot-20260102T150405Z.json --dry-run`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Run: func(cmd *cobra.Command, args []string) {
+		org := configurationObject.Options.Organization
+		snapshot, err := plan.LoadSnapshot(args[0])
+		if err != nil {
+			logrus.Fatalf("Unable to load snapshot: %v", err)
+		}
+		p, err := plan.BuildRestore(client, org, *snapshot, rollbackRegoVersion)
+		if err != nil {
+			logrus.Fatalf("Unable to plan rollback: %v", err)
+		}
+		err = plan.Print(os.Stdout, *p)
+		if err != nil {
+			logrus.Fatalf("Unable to print rollback plan: %v", err)
+		}
+		if rollbackDryRun {
+			return
+		}
+		err = plan.Apply(client, org, *p)
+		if err != nil {
+			logrus.Fatalf("Unable to roll back: %v", err)
+		}
+		logrus.Infof("Rolled back to the snapshot taken at %s.", snapshot.CreatedAt)
+	},
+}
@@ -19,8 +19,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
-	cliversion "github.com/fairwindsops/insights-cli/pkg/version"
+	"github.com/fairwindsops/insights-cli/pkg/credentials"
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/transport"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
 	"github.com/imroc/req/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -34,11 +39,22 @@ var logLevel string
 var configFile string
 var organization string
 var noDecoration bool
+var concurrency int
+var owner string
+var maxDeletes int
+var assumeYes bool
+var transportOptions = transport.DefaultOptions()
+var stopTransport = func() {}
 
 var configurationObject configuration
 
 type configuration struct {
-	Options optionConfig `yaml:"options"`
+	Options  optionConfig             `yaml:"options"`
+	Profiles map[string]profileConfig `yaml:"profiles"`
+	Values   map[string]string        `yaml:"values"`
+	// Credentials are the providers of the Insights token, tried in order,
+	// when a profile does not set its own.
+	Credentials []credentials.Provider `yaml:"credentials"`
 }
 
 type optionConfig struct {
@@ -46,6 +62,16 @@ type optionConfig struct {
 	Hostname       string `yaml:"hostname"`
 	Organization   string `yaml:"organization"`
 	RepositoryName string `yaml:"repositoryName"`
+
+	Retries         *int          `yaml:"retries"`
+	RetryMinBackoff time.Duration `yaml:"retryMinBackoff"`
+	RetryMaxBackoff time.Duration `yaml:"retryMaxBackoff"`
+	RequestTimeout  time.Duration `yaml:"requestTimeout"`
+	Deadline        time.Duration `yaml:"deadline"`
+
+	MaxDeletes *int `yaml:"maxDeletes"`
+
+	Owner string `yaml:"owner"`
 }
 
 // SetDefaults sets configuration defaults
@@ -63,12 +89,14 @@ func (c configuration) CheckForErrors() error {
 	if c.Options.Organization == "" {
 		return errors.New("options.organization not set")
 	}
+
 	return nil
 }
 
 // RUn executes the cobra root command, and returns an exit value depending on
 // whether an error occurred.
 func Run() (exitValue int) {
+	defer func() { stopTransport() }()
 	if err := rootCmd.Execute(); err != nil {
 		logrus.Error(err)
 		return 1
@@ -89,6 +117,15 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "./fairwinds-insights.yaml", "Configuration file")
 	rootCmd.PersistentFlags().StringVarP(&organization, "organization", "", "", "Fairwinds Insights Organization name")
 	rootCmd.PersistentFlags().BoolVarP(&noDecoration, "no-decoration", "", false, "Do not include decorative characters in output, such as tree visualization.")
+	rootCmd.PersistentFlags().IntVarP(&transportOptions.MaxRetries, "retries", "", transportOptions.MaxRetries, "Number of times a request to Insights is retried after a gateway error, timeout or rate limit. Overrides options.retries in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().DurationVarP(&transportOptions.MinBackoff, "retry-min-backoff", "", transportOptions.MinBackoff, "Initial wait between retries, which doubles on each retry. Overrides options.retryMinBackoff in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().DurationVarP(&transportOptions.MaxBackoff, "retry-max-backoff", "", transportOptions.MaxBackoff, "Maximum wait between retries, unless Insights asks for a longer wait. Overrides options.retryMaxBackoff in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().DurationVarP(&transportOptions.RequestTimeout, "request-timeout", "", transportOptions.RequestTimeout, "Timeout of each request to Insights, 0 for no timeout. Overrides options.requestTimeout in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().DurationVarP(&transportOptions.Deadline, "deadline", "", transportOptions.Deadline, "Overall time limit for requests to Insights, including retries, 0 for no limit. Overrides options.deadline in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().StringVarP(&owner, "owner", "", "", "Owner of the resources pushed from the push directory, recorded in its state file. With an owner, --delete only deletes resources previously pushed with the same owner. Overrides options.owner in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().IntVarP(&maxDeletes, "max-deletes", "", deletion.DefaultMaxDeletes, "Abort a push that would delete more than this number of resources of one type from Insights, or -1 for no limit. Overrides options.maxDeletes in fairwinds-insights.yaml.")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Delete resources from Insights without asking for confirmation, which is otherwise asked when standard input is a terminal.")
+	rootCmd.PersistentFlags().IntVarP(&concurrency, "concurrency", "", 1, "Maximum number of requests made to Insights at once when pushing or fetching many resources.")
 
 	logrus.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableLevelTruncation: true})
 }
@@ -100,53 +137,102 @@ func validateAndLoadInsightsAPIConfigWrapper(cmd *cobra.Command, args []string)
 	}
 }
 
-// validateAndLoadInsightsAPIConfig checks to make sure the user has set the FAIRWINDS_TOKEN environment variable and has a valid config file.
+// validateAndLoadInsightsAPIConfig checks to make sure the user has an Insights token from one of the configured credentials providers, or the FAIRWINDS_TOKEN environment variable, and has a valid config file.
 func validateAndLoadInsightsAPIConfig(client *req.Client) error {
-	insightsToken := os.Getenv("FAIRWINDS_TOKEN")
-	if insightsToken == "" {
-		return errors.New("FAIRWINDS_TOKEN must be set")
+	openErr, err := readConfigurationFile()
+	if err != nil {
+		return err
 	}
-	configHandler, err := os.Open(configFile)
-	if err == nil {
-		configContents, err := io.ReadAll(configHandler)
+	if openErr != nil && organization == "" {
+		return fmt.Errorf("please add fairwinds-insights.yaml to the base of your repository: %v", openErr)
+	}
+	// with --all-profiles, the token and organization of each profile are
+	// resolved in turn by runForProfiles
+	if !pushAllProfiles {
+		insightsToken, err := profileToken(profile)
 		if err != nil {
-			return fmt.Errorf("could not read fairwinds-insights.yaml: %v", err)
+			return err
 		}
-		err = yaml.Unmarshal(configContents, &configurationObject)
+		err = useProfile(client, profile, insightsToken)
 		if err != nil {
-			return fmt.Errorf("could not parse fairwinds-insights.yaml: %v", err)
+			return err
 		}
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("could not open fairwinds-insights.yaml: %v", err)
-	} else if organization == "" {
-		return fmt.Errorf("please add fairwinds-insights.yaml to the base of your repository: %v", err)
 	}
-	configurationObject.SetDefaults()
-	if organization != "" {
-		configurationObject.Options.Organization = organization
-	}
-	err = configurationObject.CheckForErrors()
-	if err != nil {
-		return fmt.Errorf("error parsing fairwinds-insights.yaml: %v", err)
+
+	options := configuredTransportOptions()
+	if options.MaxRetries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", options.MaxRetries)
 	}
+	stopTransport = transport.Configure(client, options)
 
-	// common client configuration
-	client.SetCommonHeaders(map[string]string{
-		"Authorization":           fmt.Sprintf("Bearer %s", insightsToken),
-		"X-Fairwinds-CLI-Version": cliversion.GetVersion(),
-	})
+	if !rootCmd.PersistentFlags().Changed("owner") {
+		owner = configurationObject.Options.Owner
+	}
+	ownership.SetOwner(owner)
 
-	client.SetBaseURL(configurationObject.Options.Hostname)
+	if !rootCmd.PersistentFlags().Changed("max-deletes") && configurationObject.Options.MaxDeletes != nil {
+		maxDeletes = *configurationObject.Options.MaxDeletes
+	}
+	deletion.SetMaxDeletes(maxDeletes)
+	deletion.SetAssumeYes(assumeYes)
 
 	return nil
 }
 
+// readConfigurationFile reads fairwinds-insights.yaml into
+// configurationObject. If the file does not exist, the error opening it is
+// returned as openErr.
+func readConfigurationFile() (openErr error, err error) {
+	configHandler, openErr := os.Open(configFile)
+	if openErr == nil {
+		configContents, err := io.ReadAll(configHandler)
+		if err != nil {
+			return nil, fmt.Errorf("could not read fairwinds-insights.yaml: %v", err)
+		}
+		err = yaml.Unmarshal(configContents, &configurationObject)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse fairwinds-insights.yaml: %v", err)
+		}
+	} else if !os.IsNotExist(openErr) {
+		return nil, fmt.Errorf("could not open fairwinds-insights.yaml: %v", openErr)
+	}
+	baseOptions = configurationObject.Options
+	return openErr, nil
+}
+
+// configuredTransportOptions returns the transport options set by flags,
+// falling back to those in fairwinds-insights.yaml.
+func configuredTransportOptions() transport.Options {
+	o := transportOptions
+	flags := rootCmd.PersistentFlags()
+	if !flags.Changed("retries") && configurationObject.Options.Retries != nil {
+		o.MaxRetries = *configurationObject.Options.Retries
+	}
+	if !flags.Changed("retry-min-backoff") && configurationObject.Options.RetryMinBackoff != 0 {
+		o.MinBackoff = configurationObject.Options.RetryMinBackoff
+	}
+	if !flags.Changed("retry-max-backoff") && configurationObject.Options.RetryMaxBackoff != 0 {
+		o.MaxBackoff = configurationObject.Options.RetryMaxBackoff
+	}
+	if !flags.Changed("request-timeout") && configurationObject.Options.RequestTimeout != 0 {
+		o.RequestTimeout = configurationObject.Options.RequestTimeout
+	}
+	if !flags.Changed("deadline") && configurationObject.Options.Deadline != 0 {
+		o.Deadline = configurationObject.Options.Deadline
+	}
+	return o
+}
+
 func preRun(cmd *cobra.Command, args []string) {
 	if noDecoration {
 		treeprint.EdgeTypeLink = " "
 		treeprint.EdgeTypeMid = "  "
 		treeprint.EdgeTypeEnd = "  "
 	}
+	if concurrency < 1 {
+		logrus.Fatalf("concurrency flag must be at least 1, got %d", concurrency)
+	}
+	workerpool.SetConcurrency(concurrency)
 	parsedLevel, err := logrus.ParseLevel(logLevel)
 	if err != nil {
 		logrus.Errorf("log-level flag has invalid value %s", logLevel)
@@ -5,16 +5,37 @@ package cli
 
 import (
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/fairwindsops/insights-cli/pkg/report"
 )
 
+var reportFormat, reportFile string
+
 // validateCmd represents the validate command
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate files for use with Insights",
 	Long:  `Validate files used with Insights, before submitting them to the Insights API`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		preRun(cmd, args)
+		if reportFormat == "" {
+			return
+		}
+		if reportFile == "" {
+			logrus.Fatal("The --report-file option is required with --report-format.")
+		}
+		err := report.Start(reportFormat)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		writeReport(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		logrus.Error("Please specify a sub-command.")
 		err := cmd.Help()
@@ -26,5 +47,26 @@ var validateCmd = &cobra.Command{
 }
 
 func init() {
+	validateCmd.PersistentFlags().StringVarP(&reportFormat, "report-format", "", "", "Also write the results to --report-file in this format, one of: "+strings.Join(report.Formats, ", ")+". Each policy or rule is a test suite, with a test case for each of its test fixtures.")
+	validateCmd.PersistentFlags().StringVarP(&reportFile, "report-file", "", "", "File to write the report given by --report-format to.")
 	rootCmd.AddCommand(validateCmd)
 }
+
+// writeReport writes the results of a validate command to --report-file,
+// if --report-format was given.
+func writeReport(cmd *cobra.Command) {
+	if reportFormat == "" {
+		return
+	}
+	err := report.WriteFile(reportFile, reportFormat, cmd.CommandPath())
+	if err != nil {
+		logrus.Fatalf("Unable to write report to %s: %v", reportFile, err)
+	}
+}
+
+// exitValidation writes the results of a validate command to --report-file,
+// if --report-format was given, and exits with code.
+func exitValidation(cmd *cobra.Command, code int) {
+	writeReport(cmd)
+	os.Exit(code)
+}
@@ -17,8 +17,10 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/report"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -97,9 +99,10 @@ var validateKyvernoPoliciesCmd = &cobra.Command{
 			}
 
 			displayValidationResults(result, []kyverno.TestResource{testResource})
+			recordValidationResults(policy.Name, kyvernoPolicyFileName, result, []kyverno.TestResource{testResource})
 			if !determineActualValidationResult(result, []kyverno.TestResource{testResource}) {
 				fmt.Println("❌ Kyverno policy validation failed.")
-				os.Exit(1)
+				exitValidation(cmd, 1)
 			}
 			fmt.Println("✅ Kyverno policy validated successfully.")
 			return
@@ -144,10 +147,12 @@ var validateKyvernoPoliciesCmd = &cobra.Command{
 				if err != nil {
 					allValid = false
 					fmt.Printf("❌ Unable to validate policy %s: %v\n", policyWithTestCases.Policy.Name, err)
+					report.Record(report.TestCase{Suite: policyWithTestCases.Policy.Name, File: policyWithTestCases.FileName, Failure: fmt.Sprintf("unable to validate policy: %v", err)})
 					continue
 				}
 
 				displayValidationResults(result, policyWithTestCases.TestCases)
+				recordValidationResults(policyWithTestCases.Policy.Name, policyWithTestCases.FileName, result, policyWithTestCases.TestCases)
 				if !determineActualValidationResult(result, policyWithTestCases.TestCases) {
 					allValid = false
 				}
@@ -156,7 +161,7 @@ var validateKyvernoPoliciesCmd = &cobra.Command{
 			if !allValid {
 				fmt.Println("\n--------------------------------")
 				fmt.Println("❌ Some Kyverno policies validation failed. Please check the output for details.")
-				os.Exit(1)
+				exitValidation(cmd, 1)
 			}
 			fmt.Println("✅ All Kyverno policies validated successfully!")
 			return
@@ -211,37 +216,9 @@ func displayValidationResults(result *kyverno.ValidationResult, testCases []kyve
 	if len(result.TestResults) == 0 && len(testCases) > 0 {
 		fmt.Printf("\n📋 Test cases:\n")
 
-		// Check if we have mixed test cases (both success and failure)
-		successCount := 0
-		failureCount := 0
-		for _, tc := range testCases {
-			switch tc.ExpectedOutcome {
-			case "success":
-				successCount++
-			case "failure":
-				failureCount++
-			default:
-				continue
-			}
-		}
-		hasMixedCases := successCount > 0 && failureCount > 0
-
-		for _, testCase := range testCases {
-			var passed bool
-			if hasMixedCases {
-				passed = actualValid
-			} else {
-				switch testCase.ExpectedOutcome {
-				case "success":
-					passed = len(result.Errors) == 0
-				case "failure":
-					passed = len(result.Errors) > 0
-				default:
-					passed = actualValid
-				}
-			}
-
-			if passed {
+		passedTestCases := fallbackTestCaseResults(result, testCases, actualValid)
+		for i, testCase := range testCases {
+			if passedTestCases[i] {
 				fmt.Printf("  ✅ %s (%s)\n", testCase.TestCaseName, testCase.FileName)
 			} else {
 				fmt.Printf("  ❌ %s (%s)\n", testCase.TestCaseName, testCase.FileName)
@@ -264,6 +241,77 @@ func displayValidationResults(result *kyverno.ValidationResult, testCases []kyve
 	}
 }
 
+// fallbackTestCaseResults returns whether each test case passed, when the
+// validation result has no TestResults to tell.
+func fallbackTestCaseResults(result *kyverno.ValidationResult, testCases []kyverno.TestResource, actualValid bool) []bool {
+	// Check if we have mixed test cases (both success and failure)
+	successCount := 0
+	failureCount := 0
+	for _, tc := range testCases {
+		switch tc.ExpectedOutcome {
+		case "success":
+			successCount++
+		case "failure":
+			failureCount++
+		default:
+			continue
+		}
+	}
+	hasMixedCases := successCount > 0 && failureCount > 0
+
+	passed := make([]bool, len(testCases))
+	for i, testCase := range testCases {
+		if hasMixedCases {
+			passed[i] = actualValid
+			continue
+		}
+		switch testCase.ExpectedOutcome {
+		case "success":
+			passed[i] = len(result.Errors) == 0
+		case "failure":
+			passed[i] = len(result.Errors) > 0
+		default:
+			passed[i] = actualValid
+		}
+	}
+	return passed
+}
+
+// recordValidationResults records each test case of a Kyverno policy for
+// reports, with the expected and actual outcome, and the errors of the
+// policy, of those that failed.
+func recordValidationResults(policyName, policyFileName string, result *kyverno.ValidationResult, testCases []kyverno.TestResource) {
+	failures := make([]string, len(testCases))
+	if len(result.TestResults) > 0 {
+		resultMap := matchTestResultsToTestCases(result, testCases)
+		for i, testCase := range testCases {
+			testResult, ok := resultMap[i]
+			if !ok {
+				failures[i] = "no result was returned for this test case"
+				continue
+			}
+			expectedSuccess := testCase.ExpectedOutcome == "success"
+			actualSuccess := testResult.ActualOutcome == "success"
+			if !testResult.Passed || expectedSuccess != actualSuccess {
+				failures[i] = fmt.Sprintf("expected outcome %s, but the outcome was %s", testCase.ExpectedOutcome, testResult.ActualOutcome)
+			}
+		}
+	} else {
+		passed := fallbackTestCaseResults(result, testCases, determineActualValidationResult(result, testCases))
+		for i, testCase := range testCases {
+			if !passed[i] {
+				failures[i] = fmt.Sprintf("expected outcome %s", testCase.ExpectedOutcome)
+			}
+		}
+	}
+	for i, testCase := range testCases {
+		if failures[i] != "" && len(result.Errors) > 0 {
+			failures[i] += "\n" + strings.Join(result.Errors, "\n")
+		}
+		report.Record(report.TestCase{Suite: policyName, Name: testCase.FileName, File: policyFileName, Failure: failures[i]})
+	}
+}
+
 // matchTestResultsToTestCases matches TestResults to TestCases using FileName and TestCaseName
 // Returns a map of test case index to its TestResult (if found)
 // Uses FileName:TestCaseName as the unique identifier to ensure correct matching
@@ -6,6 +6,8 @@ package cli
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/fairwindsops/insights-cli/pkg/opavalidation"
 	fwrego "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/rego"
@@ -13,8 +15,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var regoFileName, objectFileName, batchDir, libsDir, objectNamespaceOverride, insightsInfoCluster, insightsInfoContext, regoVersion string
+var regoFileName, objectFileName, batchDir, libsDir, clusterFixturesDir, objectNamespaceOverride, insightsInfoCluster, insightsInfoContext, regoVersion string
 var expectActionItem opavalidation.ExpectActionItemOptions
+var showOPACoverage bool
+var opaCoverageFile, opaCoverageFormat string
+var minOPACoverage float64
 
 // OPACmd represents the validate opa command
 var OPACmd = &cobra.Command{
@@ -24,7 +29,9 @@ var OPACmd = &cobra.Command{
 	Example: `
 	To validate a single policy: insights-cli validate opa -r policy.rego -k input-manifest.yaml
 
-	To validate a directory of policies and Kubernetes manifests, with a policy and its corresponding Kubernetes manifest sharing the same base filename: insights-cli validate opa -b ./all_policies`,
+	To validate a directory of policies and Kubernetes manifests, with a policy and its corresponding Kubernetes manifest sharing the same base filename: insights-cli validate opa -b ./all_policies
+
+	To also require that the manifests evaluate at least 80% of the lines of each policy, and write an lcov coverage file: insights-cli validate opa -b ./all_policies --min-coverage 80 --coverage-file coverage.lcov`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if !checkValidateOPAFlags() {
 			err := cmd.Help()
@@ -33,28 +40,76 @@ var OPACmd = &cobra.Command{
 			}
 			os.Exit(1)
 		}
+		if opaCoverageEnabled() {
+			if !slices.Contains(opavalidation.CoverageFormats, opaCoverageFormat) {
+				logrus.Fatalf("The --coverage-format option must be one of %s", strings.Join(opavalidation.CoverageFormats, ", "))
+			}
+			opavalidation.StartCoverage()
+		}
 		if regoFileName != "" {
-			_, err := opavalidation.Run(regoVersion, regoFileName, objectFileName, expectActionItem, fwrego.InsightsInfo{InsightsContext: insightsInfoContext, Cluster: insightsInfoCluster}, objectNamespaceOverride, libsDir)
+			var clusterFixtures opavalidation.ClusterFixtures
+			if clusterFixturesDir != "" {
+				var err error
+				clusterFixtures, err = opavalidation.LoadClusterFixtures(clusterFixturesDir)
+				if err != nil {
+					logrus.Fatalf("Unable to load cluster fixtures: %v", err)
+				}
+			}
+			_, err := opavalidation.Run(regoVersion, regoFileName, objectFileName, expectActionItem, fwrego.InsightsInfo{InsightsContext: insightsInfoContext, Cluster: insightsInfoCluster}, objectNamespaceOverride, libsDir, clusterFixtures)
 			if err != nil {
 				fmt.Printf("OPA policy failed validation: %v\n", err)
-				os.Exit(1)
+				reportOPACoverage()
+				exitValidation(cmd, 1)
 			}
 			fmt.Println("OPA policy validated successfully.")
 		}
 
 		if batchDir != "" {
-			_, failedPolicies, err := opavalidation.RunBatch(regoVersion, batchDir, expectActionItem, fwrego.InsightsInfo{InsightsContext: insightsInfoContext, Cluster: insightsInfoCluster}, objectNamespaceOverride, libsDir)
+			_, failedPolicies, err := opavalidation.RunBatch(regoVersion, batchDir, expectActionItem, fwrego.InsightsInfo{InsightsContext: insightsInfoContext, Cluster: insightsInfoCluster}, objectNamespaceOverride, libsDir, clusterFixturesDir)
 			fmt.Println() // separate output from RunBatch
 			if err != nil {
 				fmt.Printf("OPA policies failed validation: %v\n", err)
 				fmt.Printf("Please check the above output for details about the %s\n", opavalidation.HumanizeStringsOutput(failedPolicies, "failure"))
-				os.Exit(1)
+				reportOPACoverage()
+				exitValidation(cmd, 1)
 			}
 			fmt.Println("OPA policies validated successfully.")
 		}
+		if !reportOPACoverage() {
+			exitValidation(cmd, 1)
+		}
 	},
 }
 
+// opaCoverageEnabled returns whether any of the coverage flags of `validate
+// opa` were given.
+func opaCoverageEnabled() bool {
+	return showOPACoverage || opaCoverageFile != "" || minOPACoverage > 0
+}
+
+// reportOPACoverage prints the coverage of the validated OPA policies and
+// writes it to --coverage-file, if coverage is enabled. It returns false if
+// the coverage of any policy is below --min-coverage.
+func reportOPACoverage() bool {
+	if !opaCoverageEnabled() {
+		return true
+	}
+	coverage := opavalidation.Coverage()
+	fmt.Println() // separate coverage from validation output
+	fmt.Print(coverage.String())
+	if opaCoverageFile != "" {
+		err := coverage.WriteFile(opaCoverageFile, opaCoverageFormat)
+		if err != nil {
+			logrus.Fatalf("Unable to write coverage: %v", err)
+		}
+	}
+	below := coverage.Below(minOPACoverage)
+	for _, p := range below {
+		fmt.Printf("OPA policy %s coverage of %.1f%% is below the minimum of %.1f%%\n", p.File, p.Coverage, minOPACoverage)
+	}
+	return len(below) == 0
+}
+
 // checkValidateOPAFlags verifies supplied flags for `validate opa` are valid.
 func checkValidateOPAFlags() bool {
 	if batchDir == "" && regoFileName == "" {
@@ -84,11 +139,16 @@ func init() {
 	OPACmd.Flags().StringVarP(&regoFileName, "rego-file", "r", "", "An OPA policy file containing rego to validate. The --kube-object-file option is also required. This option validates a single policy, and is mutually exclusive with the batch-directory option.")
 	OPACmd.Flags().StringVarP(&objectFileName, "kube-object-file", "k", "", "A Kubernetes manifest to provide as input when validating a single OPA policy. This option is mutually exclusive with the batch-directory option. A manifest file ending in a .success.yaml extension is expected to return 0 action items. A manifest file ending in a .failure.yaml extension is expected to output one action item. See also the --expect-action-item option.")
 	OPACmd.Flags().StringVarP(&expectActionItem.SuccessFileExtension, "kube-manifest-success-ext", "e", ".success.yaml", "The extension for a Kubernetes manifest file name which, if found, indicates an OPA policy is NOT expected to return an action item.")
-	OPACmd.Flags().StringVarP(&expectActionItem.FailureFileExtension, "kube-manifest-failure-ext", "E", ".failure.yaml", "The extension for a Kubernetes manifest file name which, if found, indicates an OPA policy is expected to return an action item.")
+	OPACmd.Flags().StringVarP(&expectActionItem.FailureFileExtension, "kube-manifest-failure-ext", "E", ".failure.yaml", fmt.Sprintf("The extension for a Kubernetes manifest file name which, if found, indicates an OPA policy is expected to return an action item. If a file of the same name with the %s extension instead exists, the policy is expected to return the action items it lists.", opavalidation.ExpectedFileExtension))
 	OPACmd.Flags().StringVarP(&objectNamespaceOverride, "object-namespace", "N", "", "A Kubernetes namespace to override any defined in the Kubernetes object being passed as input to an OPA policy.")
 	OPACmd.Flags().StringVarP(&insightsInfoCluster, "insightsinfo-cluster", "l", "test", "A Kubernetes cluster name returned by the Insights-provided insightsinfo() rego function.")
 	OPACmd.Flags().StringVarP(&insightsInfoContext, "insightsinfo-context", "t", "Agent", "An Insights context returned by the Insights-provided insightsinfo() rego function. The context returned by Insights plugins is typically one of: CI/CD, Admission, or Agent.")
 	OPACmd.Flags().StringVarP(&libsDir, "libs-dir", "L", "", "A directory containing additional rego libraries to load. This option is not required, but can be used to load additional rego libraries.")
+	OPACmd.Flags().StringVar(&clusterFixturesDir, "cluster-fixtures", "", fmt.Sprintf("A directory containing Kubernetes manifests, returned by the Insights-provided kubernetes(kind, namespace) rego function when validating OPA policies. With the --batch-directory option, the manifests in a directory named of the form {base rego filename}%s are also returned for that policy.", opavalidation.ClusterFixturesSuffix))
 	OPACmd.Flags().BoolVarP(&expectActionItem.Default, "expect-action-item", "i", true, "Whether to expect the OPA policy to output one action item (true) or 0 action items (false). This option is applied to Kubernetes manifest files with no .success.yaml nor .failure.yaml extension.")
+	OPACmd.Flags().BoolVar(&showOPACoverage, "coverage", false, "Print the percentage of lines of each OPA policy evaluated by its Kubernetes manifests, and the lines that were not.")
+	OPACmd.Flags().StringVar(&opaCoverageFile, "coverage-file", "", "A file to write the coverage of OPA policies to, in the format given by --coverage-format.")
+	OPACmd.Flags().StringVar(&opaCoverageFormat, "coverage-format", opavalidation.CoverageFormatLCOV, fmt.Sprintf("The format of the --coverage-file. Format can be one of %s.", strings.Join(opavalidation.CoverageFormats, ", ")))
+	OPACmd.Flags().Float64Var(&minOPACoverage, "min-coverage", 0, "The minimum percentage of lines of each OPA policy that its Kubernetes manifests must evaluate. Validation fails if the coverage of any policy is lower.")
 	OPACmd.Flags().StringVarP(&regoVersion, "rego-version", "v", "v0", "The version of the rego policy to validate. This option is not required, but can be used to specify the rego version to validate. Version can be v0 or v1")
 }
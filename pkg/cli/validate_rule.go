@@ -1,19 +1,26 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/fairwindsops/insights-cli/pkg/opavalidation"
 	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-var automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext string
-var dryRun bool
+var automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext, ruleBatchDir string
+var dryRun, offlineRule bool
 
 func init() {
 	verifyRuleCmd.PersistentFlags().StringVarP(&automationRuleFilePath, "automation-rule-file", "r", "./rule.js", "Automation rule JS file path")
 	verifyRuleCmd.PersistentFlags().StringVarP(&actionItemFilePath, "action-item-file", "a", "./action-item.yaml", "Action Item file path")
 	verifyRuleCmd.PersistentFlags().StringVarP(&insightsContext, "insights-context", "t", "", "Insights context: [AdmissionController, Agent or CI/CD]")
 	verifyRuleCmd.PersistentFlags().StringVarP(&expectedActionItemFilePath, "expected-action-item", "i", "", "Optional file containing the action item that the automation rule is expected to produce")
+	verifyRuleCmd.PersistentFlags().StringVarP(&ruleBatchDir, "batch-directory", "b", "", "A directory containing automation rule .js files and their test cases, named <rule>.<case>.input.yaml for the input action item and <rule>.<case>.expected.yaml for the expected action item. This option validates multiple automation rules at once, and is mutually exclusive with the automation-rule-file, action-item-file and expected-action-item options.")
 	verifyRuleCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "", false, "Optional flag to run the rule without executing any external integration (Slack, Jira, PagerDuty, Azure, http requests).")
+	verifyRuleCmd.PersistentFlags().BoolVarP(&offlineRule, "offline", "", false, "Run the rule locally instead of with the Insights API. Integrations (Slack, Jira, PagerDuty, Azure, GitHub, http requests) are not called, and their calls are listed instead.")
 	err := verifyRuleCmd.MarkPersistentFlagRequired("insights-context")
 	if err != nil {
 		exitWithError("", err)
@@ -22,14 +29,35 @@ func init() {
 }
 
 var verifyRuleCmd = &cobra.Command{
-	Use:    "rule -t <insights context> -r <rule file> -a <action item file> [-i <expected output file> --dry-run]",
-	Short:  "Validates an automation rule",
-	Long:   "Validates an automation rule by applying it against the specified action item",
-	PreRun: validateAndLoadInsightsAPIConfigWrapper,
+	Use:   "rule -t <insights context> {-r <rule file> -a <action item file> [-i <expected output file>] | -b <directory of rules and test cases>} [--dry-run --offline]",
+	Short: "Validates an automation rule",
+	Long:  "Validates an automation rule by applying it against the specified action item",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if offlineRule {
+			return
+		}
+		validateAndLoadInsightsAPIConfigWrapper(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		org := configurationObject.Options.Organization
-		err := rules.ValidateRule(client, org, automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext, dryRun)
+		if ruleBatchDir != "" {
+			if cmd.Flags().Changed("automation-rule-file") || cmd.Flags().Changed("action-item-file") || cmd.Flags().Changed("expected-action-item") {
+				logrus.Errorln("The --batch-directory option is mutually exclusive with the --automation-rule-file, --action-item-file and --expected-action-item options.")
+				os.Exit(1)
+			}
+			_, failedCases, err := rules.RunBatch(client, org, ruleBatchDir, insightsContext, dryRun, offlineRule)
+			fmt.Println() // separate output from RunBatch
+			if err != nil {
+				fmt.Printf("Automation rules failed validation: %v\n", err)
+				fmt.Printf("Please check the above output for details about the %s\n", opavalidation.HumanizeStringsOutput(failedCases, "failure"))
+				exitValidation(cmd, 1)
+			}
+			fmt.Println("Automation rules validated successfully.")
+			return
+		}
+		err := rules.ValidateRule(client, org, automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext, dryRun, offlineRule)
 		if err != nil {
+			writeReport(cmd)
 			exitWithError("", err)
 		}
 	},
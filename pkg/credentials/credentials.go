@@ -0,0 +1,173 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credentials finds the token used to authenticate with Insights,
+// from a chain of providers.
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultEnv is the environment variable read when no providers are
+// configured.
+const DefaultEnv = "FAIRWINDS_TOKEN"
+
+// ExecTimeout limits how long a credential helper command may run.
+var ExecTimeout = time.Minute
+
+// Provider is one source of a token. Exactly one of its fields is set.
+type Provider struct {
+	// Env is an environment variable containing the token.
+	Env string `yaml:"env,omitempty"`
+	// File is a file containing the token, such as a mounted Kubernetes
+	// secret.
+	File string `yaml:"file,omitempty"`
+	// Exec is a credential helper command that prints the token.
+	Exec *Exec `yaml:"exec,omitempty"`
+}
+
+// Exec is a credential helper command, which prints the token to stdout.
+// Its stderr is shown to the user, so it can prompt or report errors.
+type Exec struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+}
+
+// String describes where the provider reads its token from, for log and
+// error messages. It never includes the token.
+func (p Provider) String() string {
+	switch {
+	case p.Env != "":
+		return "environment variable " + p.Env
+	case p.File != "":
+		return "file " + p.File
+	case p.Exec != nil:
+		return "command " + p.Exec.Command
+	}
+	return "empty provider"
+}
+
+// Validate returns an error if the provider does not set exactly one
+// source.
+func (p Provider) Validate() error {
+	set := 0
+	for _, ok := range []bool{p.Env != "", p.File != "", p.Exec != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("a credentials provider must set exactly one of env, file or exec")
+	}
+	if p.Exec != nil && p.Exec.Command == "" {
+		return errors.New("a credentials exec provider must set a command")
+	}
+	return nil
+}
+
+// errUnavailable is wrapped by the errors of providers that have no token,
+// such as an unset environment variable, so the next provider is tried.
+var errUnavailable = errors.New("token unavailable")
+
+// Token returns the token of the first provider in chain that has one, or
+// of the DefaultEnv environment variable if chain is empty. A provider
+// that fails, such as a credential helper that exits with an error, stops
+// the chain.
+func Token(chain []Provider) (string, error) {
+	if len(chain) == 0 {
+		chain = []Provider{{Env: DefaultEnv}}
+	}
+	var reasons []string
+	for _, p := range chain {
+		if err := p.Validate(); err != nil {
+			return "", err
+		}
+		token, err := p.token()
+		if errors.Is(err, errUnavailable) {
+			logrus.Debugf("No Insights token from %s", p)
+			reasons = append(reasons, strings.TrimSuffix(err.Error(), ": "+errUnavailable.Error()))
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to get Insights token from %s: %w", p, err)
+		}
+		logrus.Debugf("Using Insights token from %s", p)
+		return token, nil
+	}
+	return "", errors.New(strings.Join(reasons, ", or "))
+}
+
+func (p Provider) token() (string, error) {
+	switch {
+	case p.Env != "":
+		token := strings.TrimSpace(os.Getenv(p.Env))
+		if token == "" {
+			return "", fmt.Errorf("%s must be set: %w", p.Env, errUnavailable)
+		}
+		return token, nil
+	case p.File != "":
+		b, err := os.ReadFile(p.File)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s must exist: %w", p.File, errUnavailable)
+		}
+		if err != nil {
+			return "", err
+		}
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return "", errors.New("file is empty")
+		}
+		return token, nil
+	}
+	return p.Exec.token()
+}
+
+// token runs the credential helper. Its output is not included in errors,
+// as it may contain the token.
+func (e Exec) token() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ExecTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Env = os.Environ()
+	for name, value := range e.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("command did not finish within %s", ExecTimeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.New("command printed no token")
+	}
+	return token, nil
+}
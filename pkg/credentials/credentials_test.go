@@ -0,0 +1,47 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+	t.Setenv("TEST_INSIGHTS_TOKEN", "env-token")
+	t.Setenv("TEST_UNSET_TOKEN", "")
+	t.Setenv(DefaultEnv, "default-token")
+
+	testCases := []struct {
+		name    string
+		chain   []Provider
+		want    string
+		wantErr string
+	}{
+		{name: "default", want: "default-token"},
+		{name: "env", chain: []Provider{{Env: "TEST_INSIGHTS_TOKEN"}}, want: "env-token"},
+		{name: "file", chain: []Provider{{File: tokenFile}}, want: "file-token"},
+		{name: "exec", chain: []Provider{{Exec: &Exec{Command: "sh", Args: []string{"-c", "echo $TOKEN"}, Env: map[string]string{"TOKEN": "exec-token"}}}}, want: "exec-token"},
+		{name: "falls through unavailable providers", chain: []Provider{{Env: "TEST_UNSET_TOKEN"}, {File: filepath.Join(dir, "missing")}, {File: tokenFile}}, want: "file-token"},
+		{name: "none available", chain: []Provider{{Env: "TEST_UNSET_TOKEN"}, {File: filepath.Join(dir, "missing")}}, wantErr: "TEST_UNSET_TOKEN must be set, or " + filepath.Join(dir, "missing") + " must exist"},
+		{name: "failing command stops the chain", chain: []Provider{{Exec: &Exec{Command: "sh", Args: []string{"-c", "echo secret-output; exit 1"}}}, {File: tokenFile}}, wantErr: "unable to get Insights token from command sh: exit status 1"},
+		{name: "command without output", chain: []Provider{{Exec: &Exec{Command: "true"}}}, wantErr: "command printed no token"},
+		{name: "more than one source", chain: []Provider{{Env: "TEST_INSIGHTS_TOKEN", File: tokenFile}}, wantErr: "exactly one of env, file or exec"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := Token(tc.chain)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				assert.NotContains(t, err.Error(), "secret-output")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, token)
+		})
+	}
+}
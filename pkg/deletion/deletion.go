@@ -0,0 +1,87 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deletion guards against pushes deleting more resources from
+// Insights than intended, such as every policy in an organization after a
+// push from the wrong directory.
+package deletion
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultMaxDeletes is the number of resources of one type a push may delete
+// unless another maximum is set.
+const DefaultMaxDeletes = 20
+
+var maxDeletes = DefaultMaxDeletes
+var assumeYes bool
+
+var input io.Reader = os.Stdin
+var output io.Writer = os.Stderr
+var isTerminal = stdinIsTerminal
+
+// SetMaxDeletes sets the number of resources of one type a push may delete.
+// A negative maximum disables the limit.
+func SetMaxDeletes(n int) {
+	maxDeletes = n
+}
+
+// SetAssumeYes sets whether deletions are made without asking for
+// confirmation.
+func SetAssumeYes(yes bool) {
+	assumeYes = yes
+}
+
+// Confirm returns an error if deleting the named resources would exceed the
+// maximum set by SetMaxDeletes. Unless this is a dry run, SetAssumeYes was
+// given true or standard input is not a terminal, it also lists the resources
+// and returns an error if the deletion is not confirmed.
+func Confirm(description string, names []string, dryRun bool) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if maxDeletes >= 0 && len(names) > maxDeletes {
+		return fmt.Errorf("refusing to delete %d %s, more than the maximum of %d: check the push directory, or set --max-deletes", len(names), description, maxDeletes)
+	}
+	if dryRun || assumeYes || !isTerminal() {
+		return nil
+	}
+	fmt.Fprintf(output, "The following %d %s will be deleted from Insights:\n", len(names), description)
+	for _, name := range names {
+		fmt.Fprintf(output, "  - %s\n", name)
+	}
+	fmt.Fprint(output, "Delete them? [y/N] ")
+	answer, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("unable to read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("deletion of %s was not confirmed, use --yes to skip confirmation", description)
+}
+
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
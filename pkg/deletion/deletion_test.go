@@ -0,0 +1,39 @@
+package deletion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	defer SetMaxDeletes(DefaultMaxDeletes)
+	defer SetAssumeYes(false)
+	defer func(f func() bool) { isTerminal = f }(isTerminal)
+	var out bytes.Buffer
+	output = &out
+	isTerminal = func() bool { return false }
+	names := []string{"a", "b", "c"}
+
+	assert.NoError(t, Confirm("OPA policies", nil, false))
+	assert.NoError(t, Confirm("OPA policies", names, false), "not a terminal")
+	SetMaxDeletes(2)
+	assert.Error(t, Confirm("OPA policies", names, false))
+	assert.Error(t, Confirm("OPA policies", names, true), "the maximum applies to dry runs")
+	SetMaxDeletes(-1)
+	assert.NoError(t, Confirm("OPA policies", names, false))
+
+	isTerminal = func() bool { return true }
+	input = strings.NewReader("n\n")
+	assert.Error(t, Confirm("OPA policies", names, false))
+	assert.Contains(t, out.String(), "  - b\n")
+	input = strings.NewReader("yes\n")
+	assert.NoError(t, Confirm("OPA policies", names, false))
+	input = strings.NewReader("")
+	assert.Error(t, Confirm("OPA policies", names, false))
+	assert.NoError(t, Confirm("OPA policies", names, true), "dry runs are not confirmed")
+	SetAssumeYes(true)
+	assert.NoError(t, Confirm("OPA policies", names, false))
+}
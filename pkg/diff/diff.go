@@ -0,0 +1,125 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff renders unified diffs between resources in Insights and their
+// local counterparts.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// Diff holds the remote and local content of an updated resource. Format is
+// the kind of content, such as rego, yaml or js.
+type Diff struct {
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	Format   string `json:"format"`
+	Remote   string `json:"remote"`
+	Local    string `json:"local"`
+}
+
+// YAML returns a Diff of the YAML representation of remote and local.
+func YAML(resource, name string, remote, local any) (Diff, error) {
+	remoteBytes, err := yaml.Marshal(remote)
+	if err != nil {
+		return Diff{}, fmt.Errorf("unable to marshal remote %s %s: %w", resource, name, err)
+	}
+	localBytes, err := yaml.Marshal(local)
+	if err != nil {
+		return Diff{}, fmt.Errorf("unable to marshal local %s %s: %w", resource, name, err)
+	}
+	return Diff{Resource: resource, Name: name, Format: "yaml", Remote: string(remoteBytes), Local: string(localBytes)}, nil
+}
+
+// Unified returns the diff in unified format, or an empty string if the
+// remote and local content are the same.
+func (d Diff) Unified() (string, error) {
+	if d.Remote == d.Local {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(d.Remote),
+		B:        splitLines(d.Local),
+		FromFile: fmt.Sprintf("insights/%s.%s", d.Name, d.Format),
+		ToFile:   fmt.Sprintf("local/%s.%s", d.Name, d.Format),
+		Context:  3,
+	})
+}
+
+// splitLines splits s into newline terminated lines, so the last line does
+// not run into the following line of the diff.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	lines := strings.SplitAfter(s, "\n")
+	return lines[:len(lines)-1]
+}
+
+// Colorize colors the added, removed and hunk header lines of a unified
+// diff. Colors are omitted when the output is not a terminal.
+func Colorize(unified string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			sb.WriteString(color.New(color.Bold).Sprint(line))
+		case strings.HasPrefix(line, "+"):
+			sb.WriteString(color.GreenString("%s", line))
+		case strings.HasPrefix(line, "-"):
+			sb.WriteString(color.RedString("%s", line))
+		case strings.HasPrefix(line, "@@"):
+			sb.WriteString(color.CyanString("%s", line))
+		default:
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+// Print writes the colorized unified form of each diff to w.
+func Print(w io.Writer, diffs []Diff) error {
+	for _, d := range diffs {
+		unified, err := d.Unified()
+		if err != nil {
+			return fmt.Errorf("unable to diff %s %s: %w", d.Resource, d.Name, err)
+		}
+		if unified == "" {
+			continue
+		}
+		_, err = fmt.Fprint(w, Colorize(unified))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForName returns the diffs of the named resource.
+func ForName(diffs []Diff, name string) []Diff {
+	return lo.Filter(diffs, func(d Diff, _ int) bool {
+		return d.Name == name
+	})
+}
@@ -0,0 +1,47 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	d := Diff{Resource: "OPA policy", Name: "check1", Format: "rego", Remote: "package a\nallow = true", Local: "package a\nallow = false\n"}
+	unified, err := d.Unified()
+	assert.NoError(t, err)
+	assert.Equal(t, `--- insights/check1.rego
++++ local/check1.rego
+@@ -1,2 +1,2 @@
+ package a
+-allow = true
++allow = false
+`, unified)
+
+	d.Local = d.Remote
+	unified, err = d.Unified()
+	assert.NoError(t, err)
+	assert.Empty(t, unified)
+}
+
+func TestYAML(t *testing.T) {
+	d, err := YAML("app-group", "group1", map[string]any{"type": "AppGroup", "kinds": []string{"Deployment"}}, map[string]any{"type": "AppGroup", "kinds": []string{"StatefulSet"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml", d.Format)
+	unified, err := d.Unified()
+	assert.NoError(t, err)
+	assert.Contains(t, unified, "-    - Deployment\n+    - StatefulSet\n")
+}
+
+func TestColorize(t *testing.T) {
+	color.NoColor = true
+	unified := "--- a\n+++ b\n@@ -1 +1 @@\n-x\n+y\n"
+	assert.Equal(t, unified, Colorize(unified))
+}
+
+func TestForName(t *testing.T) {
+	diffs := []Diff{{Name: "a"}, {Name: "b"}, {Name: "a", Format: "js"}}
+	assert.Len(t, ForName(diffs, "a"), 2)
+	assert.Empty(t, ForName(diffs, "c"))
+}
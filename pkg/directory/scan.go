@@ -24,8 +24,9 @@ import (
 )
 
 // ScanOPAFolder looks through a given folder and returns a map[string][]string
-// keyed on the OPA policy name, and the value listing files providing rego
-// and V1 yaml instances for that policy.
+// keyed on the OPA policy name, and the value listing files providing rego,
+// settings and yaml instances for that policy. Instances are in an instances
+// sub-directory of the policy directory.
 func ScanOPAFolder(folder string) (map[string][]string, error) {
 	fileMap := map[string][]string{}
 	regoFiles, err := findRegoFilesOtherThanPolicy(folder)
@@ -58,7 +59,12 @@ func ScanOPAFolder(folder string) (map[string][]string, error) {
 		if filepath.Dir(path) == folder { // Any top-level .rego files are already processed
 			return nil
 		}
-		policyName := filepath.Base(filepath.Dir(path))
+		policyDir := filepath.Dir(path)
+		if filepath.Base(policyDir) == "instances" && filepath.Dir(policyDir) != folder {
+			// instances of a policy are in a sub-directory of its directory
+			policyDir = filepath.Dir(policyDir)
+		}
+		policyName := filepath.Base(policyDir)
 		fileMap[policyName] = append(fileMap[policyName], path)
 		return nil
 	})
@@ -0,0 +1,129 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filter selects the resources pushed to Insights by name and
+// labels, so that a push of part of a directory leaves the rest alone.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Filter selects resources whose name matches one of Only, if any, and none
+// of Exclude, and whose labels match Selector, if set.
+type Filter struct {
+	Only     []string
+	Exclude  []string
+	Selector labels.Selector
+}
+
+// New returns a Filter of the given name globs, in the syntax of path.Match,
+// and label selector, in the syntax of kubectl --selector.
+func New(only, exclude []string, selector string) (Filter, error) {
+	for _, pattern := range append(append([]string{}, only...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Filter{}, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+	}
+	f := Filter{Only: only, Exclude: exclude}
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+		f.Selector = s
+	}
+	return f, nil
+}
+
+// Active returns true if the filter excludes any resources.
+func (f Filter) Active() bool {
+	return len(f.Only) > 0 || len(f.Exclude) > 0 || (f.Selector != nil && !f.Selector.Empty())
+}
+
+// Matches returns true if the resource with the given name and labels is
+// selected by the filter.
+func (f Filter) Matches(name string, resourceLabels map[string]string) bool {
+	return f.matchesName(name) && (f.Selector == nil || f.Selector.Matches(labels.Set(resourceLabels)))
+}
+
+// MatchesRemote returns true if the resource with the given name, which is
+// only in Insights, is selected by the filter. Labels are only kept in local
+// files, so such a resource is never selected while a label selector is set.
+func (f Filter) MatchesRemote(name string) bool {
+	return f.matchesName(name) && (f.Selector == nil || f.Selector.Empty())
+}
+
+func (f Filter) matchesName(name string) bool {
+	if len(f.Only) > 0 && !matchesAny(f.Only, name) {
+		return false
+	}
+	return !matchesAny(f.Exclude, name)
+}
+
+// String describes the filter, and is empty if it is not active.
+func (f Filter) String() string {
+	if !f.Active() {
+		return ""
+	}
+	selector := ""
+	if f.Selector != nil {
+		selector = f.Selector.String()
+	}
+	return fmt.Sprintf("only=%s exclude=%s selector=%s", strings.Join(f.Only, ","), strings.Join(f.Exclude, ","), selector)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var current Filter
+
+// Set sets the filter applied to resources pushed to Insights.
+func Set(f Filter) {
+	current = f
+}
+
+// Active returns true if the filter set by Set excludes any resources.
+func Active() bool {
+	return current.Active()
+}
+
+// Matches returns true if the resource with the given name and labels is
+// selected by the filter set by Set. Every resource is selected if no filter
+// is set. Resources that are not selected are neither pushed nor deleted.
+func Matches(name string, resourceLabels map[string]string) bool {
+	return current.Matches(name, resourceLabels)
+}
+
+// MatchesRemote returns true if the resource with the given name, which is
+// only in Insights, is selected by the filter set by Set. Such resources are
+// not deleted while a label selector is set, as their labels are unknown.
+func MatchesRemote(name string) bool {
+	return current.MatchesRemote(name)
+}
+
+// String describes the filter set by Set.
+func String() string {
+	return current.String()
+}
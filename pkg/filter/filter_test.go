@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	f, err := New(nil, nil, "")
+	assert.NoError(t, err)
+	assert.False(t, f.Active())
+	assert.True(t, f.Matches("anything", nil))
+
+	f, err = New([]string{"require-*", "disallow-privileged"}, []string{"require-labels"}, "")
+	assert.NoError(t, err)
+	assert.True(t, f.Active())
+	assert.True(t, f.Matches("require-requests", nil))
+	assert.True(t, f.Matches("disallow-privileged", nil))
+	assert.False(t, f.Matches("require-labels", nil))
+	assert.False(t, f.Matches("other", nil))
+
+	f, err = New(nil, nil, "team=platform,tier!=experimental")
+	assert.NoError(t, err)
+	assert.True(t, f.Active())
+	assert.True(t, f.Matches("a", map[string]string{"team": "platform"}))
+	assert.False(t, f.Matches("a", map[string]string{"team": "platform", "tier": "experimental"}))
+	assert.False(t, f.Matches("a", nil))
+}
+
+func TestMatchesRemote(t *testing.T) {
+	f, err := New([]string{"require-*"}, []string{"require-labels"}, "")
+	assert.NoError(t, err)
+	assert.True(t, f.MatchesRemote("require-requests"))
+	assert.False(t, f.MatchesRemote("require-labels"))
+	assert.False(t, f.MatchesRemote("other"))
+
+	f, err = New(nil, nil, "team=platform")
+	assert.NoError(t, err)
+	assert.False(t, f.MatchesRemote("require-requests"), "labels of resources only in Insights are unknown")
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New([]string{"["}, nil, "")
+	assert.Error(t, err)
+	_, err = New(nil, nil, "team in (")
+	assert.Error(t, err)
+}
@@ -15,17 +15,26 @@
 package kyverno
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
+	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"github.com/xlab/treeprint"
 
 	"github.com/imroc/req/v3"
 	"gopkg.in/yaml.v3"
+
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/variables"
 )
 
 // AddKyvernoPoliciesBranch builds a tree for Kyverno policies
@@ -72,8 +81,130 @@ func AddClusterKyvernoPoliciesWithAppGroupsBranch(client *req.Client, org, clust
 	return nil
 }
 
-// PushKyvernoPolicies pushes Kyverno policies to insights using bulk API
-func PushKyvernoPolicies(client *req.Client, policies []KyvernoPolicy, org string, deleteMissing, dryRun bool) error {
+// Plan holds the Kyverno policies that will be bulk upserted by ApplyPlan, the
+// names of the policies that will be created, updated or deleted, and a
+// fingerprint of the policies in Insights at the time the plan was built.
+type Plan struct {
+	Policies          []KyvernoPolicy `json:"policies"`
+	Inserts           []string        `json:"inserts"`
+	Updates           []string        `json:"updates"`
+	Deletes           []string        `json:"deletes"`
+	DeleteMissing     bool            `json:"deleteMissing"`
+	RemoteFingerprint string          `json:"remoteFingerprint"`
+}
+
+// BuildPlan compares the given Kyverno policies with those in Insights.
+// Policies not selected by the push filter are left out. As policies are
+// deleted by the bulk upsert, deleteMissing is ignored while a filter is
+// active, so filtered out policies are not deleted.
+func BuildPlan(client *req.Client, policies []KyvernoPolicy, org string, deleteMissing bool) (*Plan, error) {
+	if filter.Active() {
+		policies = lo.Filter(policies, func(p KyvernoPolicy, _ int) bool { return filter.Matches(p.Name, stringLabels(p.Labels)) })
+		if deleteMissing {
+			logrus.Warn("Kyverno policies are not deleted when pushing with --policies, --only, --exclude or --selector")
+			deleteMissing = false
+		}
+	}
+	existingPolicies, err := FetchKyvernoPolicies(client, org)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := utils.Fingerprint(existingPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint Kyverno policies: %w", err)
+	}
+	plan := Plan{Policies: policies, DeleteMissing: deleteMissing, RemoteFingerprint: fingerprint}
+	existingByName := lo.KeyBy(existingPolicies, func(p KyvernoPolicy) string { return p.Name })
+	policiesByName := lo.KeyBy(policies, func(p KyvernoPolicy) string { return p.Name })
+	for _, policy := range policies {
+		existing, found := existingByName[policy.Name]
+		if !found {
+			plan.Inserts = append(plan.Inserts, policy.Name)
+			continue
+		}
+		if policyNeedsUpdate(policy, existing) {
+			plan.Updates = append(plan.Updates, policy.Name)
+		}
+	}
+	if deleteMissing {
+		for _, existing := range existingPolicies {
+			if _, found := policiesByName[existing.Name]; !found {
+				plan.Deletes = append(plan.Deletes, existing.Name)
+			}
+		}
+	}
+	return &plan, nil
+}
+
+// stringLabels returns the labels of a policy with string values, which are
+// the only ones a label selector can match.
+func stringLabels(labels map[string]any) map[string]string {
+	result := map[string]string{}
+	for k, v := range labels {
+		if s, ok := v.(string); ok {
+			result[k] = s
+		}
+	}
+	return result
+}
+
+// policyNeedsUpdate compares the fields of a policy that are pushed to
+// Insights. Both policies are normalized through JSON so YAML and API number
+// types compare equal.
+func policyNeedsUpdate(filePolicy, existingPolicy KyvernoPolicy) bool {
+	normalize := func(p KyvernoPolicy) any {
+		var v any
+		b, err := json.Marshal([]any{p.Kind, p.APIVersion, p.Spec, p.Labels, p.Annotations})
+		if err != nil {
+			return nil
+		}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil
+		}
+		return v
+	}
+	return !reflect.DeepEqual(normalize(filePolicy), normalize(existingPolicy))
+}
+
+// RemoteFingerprint returns the fingerprint of the Kyverno policies in Insights
+// covered by the plan, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	existingPolicies, err := FetchKyvernoPolicies(client, org)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := utils.Fingerprint(existingPolicies)
+	if err != nil {
+		return "", fmt.Errorf("unable to fingerprint Kyverno policies: %w", err)
+	}
+	return fingerprint, nil
+}
+
+// VerifyPlan returns an error if the Kyverno policies in Insights have changed
+// since the plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
+	if err != nil {
+		return err
+	}
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("kyverno policies in Insights have changed since the plan was created")
+	}
+	return nil
+}
+
+// ApplyPlan bulk upserts the policies described by the plan in Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	err := deletion.Confirm("Kyverno policies", plan.Deletes, dryRun)
+	if err != nil {
+		return err
+	}
+	return pushKyvernoPolicies(client, plan.Policies, org, plan.DeleteMissing, dryRun)
+}
+
+// pushKyvernoPolicies bulk upserts policies, deleting those in Insights that
+// are not given if deleteMissing is true.
+func pushKyvernoPolicies(client *req.Client, policies []KyvernoPolicy, org string, deleteMissing, dryRun bool) error {
 	logrus.Debugln("Pushing Kyverno policies")
 
 	if dryRun {
@@ -109,8 +240,25 @@ func PushKyvernoPolicies(client *req.Client, policies []KyvernoPolicy, org strin
 
 // GetPolicyFilesForPush gets only policy files (excluding test cases) for push operations
 func GetPolicyFilesForPush(policyDir string) ([]KyvernoPolicy, error) {
+	paths, err := GetPolicyFilePathsForPush(policyDir)
+	if err != nil {
+		return nil, err
+	}
 	var policies []KyvernoPolicy
+	for _, path := range paths {
+		policy, err := readPolicyFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading policy file %s: %w", filepath.Base(path), err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
 
+// GetPolicyFilePathsForPush returns the paths of the policy files, excluding
+// test cases, read by GetPolicyFilesForPush.
+func GetPolicyFilePathsForPush(policyDir string) ([]string, error) {
+	var paths []string
 	err := filepath.Walk(policyDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return fmt.Errorf("error walking directory %s: %w", path, err)
@@ -124,17 +272,13 @@ func GetPolicyFilesForPush(policyDir string) ([]KyvernoPolicy, error) {
 
 		// Only process policy files, exclude test case files
 		if isPolicyFile(filename) && !isTestCaseFile(filename) {
-			policy, err := readPolicyFromFile(path)
-			if err != nil {
-				return fmt.Errorf("error reading policy file %s: %w", filename, err)
-			}
-			policies = append(policies, policy)
+			paths = append(paths, path)
 		}
 
 		return nil
 	})
 
-	return policies, err
+	return paths, err
 }
 
 // DiscoverPoliciesAndTestCases discovers all policies and their associated test cases
@@ -185,6 +329,7 @@ func DiscoverPoliciesAndTestCases(policyDir string) ([]PolicyWithTestCases, erro
 				}
 			}
 			policyMap[policyName].Policy = policy
+			policyMap[policyName].FileName = path
 		}
 
 		return nil
@@ -280,7 +425,7 @@ func readPolicyFromFile(filePath string) (KyvernoPolicy, error) {
 		return KyvernoPolicy{}, err
 	}
 
-	fileContents, err := os.ReadFile(filePath)
+	fileContents, err := variables.ReadFile(filePath)
 	if err != nil {
 		return KyvernoPolicy{}, fmt.Errorf("failed to read policy file %s: %w", filePath, err)
 	}
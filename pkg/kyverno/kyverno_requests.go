@@ -46,8 +46,7 @@ func FetchKyvernoPolicies(client *req.Client, org string) ([]KyvernoPolicy, erro
 	}
 	var policyList KyvernoPolicyList
 	if resp.IsErrorState() {
-		logrus.Errorf("FetchKyvernoPolicies: invalid response code: %s %v", string(resp.Bytes()), resp.StatusCode)
-		return nil, errors.New("FetchKyvernoPolicies: invalid response code")
+		return nil, fmt.Errorf("FetchKyvernoPolicies: invalid response code %d: %s", resp.StatusCode, string(resp.Bytes()))
 	}
 	err = resp.Unmarshal(&policyList)
 	if err != nil {
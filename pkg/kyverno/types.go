@@ -178,6 +178,7 @@ type TestResult struct {
 // PolicyWithTestCases represents a policy with its associated test cases
 type PolicyWithTestCases struct {
 	Policy    KyvernoPolicy
+	FileName  string
 	TestCases []TestResource
 }
 
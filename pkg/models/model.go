@@ -37,6 +37,10 @@ type CustomCheckModel struct {
 	Instances   []CustomCheckInstanceModel `json:"-" yaml:"-"`
 	Description string
 	Disabled    *bool
+	Labels      map[string]string `json:"-" yaml:"-"`
+	// RegoVersion overrides the rego version of the push, when restoring a
+	// check whose version was recorded in a snapshot.
+	RegoVersion string `json:",omitempty" yaml:"-"`
 }
 
 // CustomCheckInstanceModel is a model for the API endpoint to receive an Instance for a Custom Check in OPA
@@ -0,0 +1,105 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opa
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/imroc/req/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/fairwindsops/insights-cli/pkg/models"
+)
+
+// DownloadChecks writes the OPA checks in Insights to saveDir, in the layout
+// read by BuildPlan: a directory per check holding its policy.rego, a
+// .policy-settings.yaml with its output settings if it has any, and an
+// instances directory with a YAML file per instance. It returns the number of
+// checks written.
+func DownloadChecks(client *req.Client, org, saveDir string) (int, error) {
+	apiChecks, apiInstances, err := fetchChecksAndInstances(client, org, nil, true)
+	if err != nil {
+		return 0, err
+	}
+	for _, apiCheck := range apiChecks {
+		check := checkModelFromAPI(apiCheck, apiInstances)
+		err := writeCheck(saveDir, check)
+		if err != nil {
+			return 0, fmt.Errorf("error writing OPA policy %s: %w", apiCheck.Name, err)
+		}
+	}
+	return len(apiChecks), nil
+}
+
+func writeCheck(saveDir string, check models.CustomCheckModel) error {
+	err := validateFileName(check.CheckName)
+	if err != nil {
+		return err
+	}
+	checkDir := filepath.Join(saveDir, check.CheckName)
+	err = os.MkdirAll(checkDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(checkDir, "policy.rego"), []byte(check.Rego), 0644)
+	if err != nil {
+		return err
+	}
+	if check.Output != (models.OutputModel{}) {
+		err = writeYAML(filepath.Join(checkDir, policySettingsFileName), policyMetadata{Output: check.Output})
+		if err != nil {
+			return err
+		}
+	}
+	if len(check.Instances) == 0 {
+		return nil
+	}
+	instancesDir := filepath.Join(checkDir, instancesDirName)
+	err = os.MkdirAll(instancesDir, 0755)
+	if err != nil {
+		return err
+	}
+	for _, instance := range check.Instances {
+		err := validateFileName(instance.InstanceName)
+		if err != nil {
+			return err
+		}
+		err = writeYAML(filepath.Join(instancesDir, instance.InstanceName+".yaml"), instance)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateFileName returns an error if name can not be used as-is as a
+// directory or file name, as the name is read back from the file name.
+func validateFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%q can not be used as a file name", name)
+	}
+	return nil
+}
+
+func writeYAML(filePath string, v any) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, b, 0644)
+}
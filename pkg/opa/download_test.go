@@ -0,0 +1,66 @@
+package opa
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fairwindsops/insights-plugins/plugins/opa/pkg/opa"
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadChecksRoundTrip(t *testing.T) {
+	title := "Missing label"
+	severity := 0.7
+	checks := []opa.OPACustomCheck{
+		{Name: "needs-label", Version: 2, Rego: check},
+		{Name: "v1-check", Version: 1, Rego: "package fairwinds\n", Title: &title, Severity: &severity},
+	}
+	instance := opa.CheckSetting{CheckName: "v1-check", Targets: []string{"apps/Deployment", "apps/StatefulSet", "/Pod"}}
+	instance.AdditionalData.Name = "team-label"
+	instance.AdditionalData.Parameters = map[string]any{"label": "team", "minimum": 2}
+	instance.AdditionalData.Output.Severity = &severity
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body any
+		switch r.URL.Path {
+		case "/v0/organizations/acme/opa/customChecks":
+			body = checks
+		case "/v0/organizations/acme/opa/customChecks/v1-check/instances":
+			body = []opa.CheckSetting{instance}
+		case "/v0/organizations/acme/opa/customChecks/needs-label/instances":
+			body = []opa.CheckSetting{}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(body))
+	}))
+	defer server.Close()
+	client := req.C().SetBaseURL(server.URL)
+
+	dir := t.TempDir()
+	n, err := DownloadChecks(client, "acme", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	rego, err := os.ReadFile(filepath.Join(dir, "needs-label", "policy.rego"))
+	assert.NoError(t, err)
+	assert.Equal(t, check, string(rego))
+	assert.FileExists(t, filepath.Join(dir, "v1-check", ".policy-settings.yaml"))
+	assert.FileExists(t, filepath.Join(dir, "v1-check", "instances", "team-label.yaml"))
+	assert.NoFileExists(t, filepath.Join(dir, "needs-label", ".policy-settings.yaml"))
+	assert.NoDirExists(t, filepath.Join(dir, "needs-label", "instances"))
+
+	plan, err := BuildPlan(client, dir, "acme", true, "v0")
+	assert.NoError(t, err)
+	assert.Equal(t, CompareResults{}, plan.Changes)
+}
+
+func TestWriteCheckRejectsUnsafeNames(t *testing.T) {
+	err := writeCheck(t.TempDir(), checkModelFromAPI(opa.OPACustomCheck{Name: "../escape"}, nil))
+	assert.Error(t, err)
+}
@@ -15,8 +15,8 @@
 package opa
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
@@ -27,8 +27,14 @@ import (
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"github.com/xlab/treeprint"
+	"gopkg.in/yaml.v3"
 
+	"github.com/fairwindsops/insights-cli/pkg/diff"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
 	"github.com/fairwindsops/insights-cli/pkg/models"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
 )
 
 // CompareResults shows the results of a comparison between what's present in the API and what's in a folder
@@ -39,38 +45,117 @@ type CompareResults struct {
 	InstanceInsert []models.CustomCheckInstanceModel
 	InstanceUpdate []models.CustomCheckInstanceModel
 	InstanceDelete []models.CustomCheckInstanceModel
+	Diffs          []diff.Diff
 }
 
-// CompareChecks compares a folder vs the checks returned by the API.
-func CompareChecks(client *req.Client, folder, org string, fileChecks []models.CustomCheckModel, deleteMissing bool) (CompareResults, error) {
-	var results CompareResults
+// MarshalJSON includes the check and instance names, which are otherwise
+// omitted from the JSON representation of the models.
+func (r CompareResults) MarshalJSON() ([]byte, error) {
+	return json.Marshal(compareResultsJSON{
+		CheckInsert:    namedChecks(r.CheckInsert),
+		CheckUpdate:    namedChecks(r.CheckUpdate),
+		CheckDelete:    namedChecks(r.CheckDelete),
+		InstanceInsert: namedInstances(r.InstanceInsert),
+		InstanceUpdate: namedInstances(r.InstanceUpdate),
+		InstanceDelete: namedInstances(r.InstanceDelete),
+		Diffs:          r.Diffs,
+	})
+}
+
+// UnmarshalJSON is the counterpart of MarshalJSON.
+func (r *CompareResults) UnmarshalJSON(b []byte) error {
+	var j compareResultsJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	r.CheckInsert = checkModels(j.CheckInsert)
+	r.CheckUpdate = checkModels(j.CheckUpdate)
+	r.CheckDelete = checkModels(j.CheckDelete)
+	r.InstanceInsert = instanceModels(j.InstanceInsert)
+	r.InstanceUpdate = instanceModels(j.InstanceUpdate)
+	r.InstanceDelete = instanceModels(j.InstanceDelete)
+	r.Diffs = j.Diffs
+	return nil
+}
+
+type compareResultsJSON struct {
+	CheckInsert    []namedCheck    `json:"checkInsert"`
+	CheckUpdate    []namedCheck    `json:"checkUpdate"`
+	CheckDelete    []namedCheck    `json:"checkDelete"`
+	InstanceInsert []namedInstance `json:"instanceInsert"`
+	InstanceUpdate []namedInstance `json:"instanceUpdate"`
+	InstanceDelete []namedInstance `json:"instanceDelete"`
+	Diffs          []diff.Diff     `json:"diffs"`
+}
+
+type namedCheck struct {
+	CheckName string
+	models.CustomCheckModel
+}
+
+type namedInstance struct {
+	CheckName    string
+	InstanceName string
+	models.CustomCheckInstanceModel
+}
+
+func namedChecks(checks []models.CustomCheckModel) []namedCheck {
+	return lo.Map(checks, func(c models.CustomCheckModel, _ int) namedCheck {
+		return namedCheck{CheckName: c.CheckName, CustomCheckModel: c}
+	})
+}
+
+func checkModels(checks []namedCheck) []models.CustomCheckModel {
+	return lo.Map(checks, func(c namedCheck, _ int) models.CustomCheckModel {
+		c.CustomCheckModel.CheckName = c.CheckName
+		return c.CustomCheckModel
+	})
+}
+
+func namedInstances(instances []models.CustomCheckInstanceModel) []namedInstance {
+	return lo.Map(instances, func(i models.CustomCheckInstanceModel, _ int) namedInstance {
+		return namedInstance{CheckName: i.CheckName, InstanceName: i.InstanceName, CustomCheckInstanceModel: i}
+	})
+}
+
+func instanceModels(instances []namedInstance) []models.CustomCheckInstanceModel {
+	return lo.Map(instances, func(i namedInstance, _ int) models.CustomCheckInstanceModel {
+		i.CustomCheckInstanceModel.CheckName = i.CheckName
+		i.CustomCheckInstanceModel.InstanceName = i.InstanceName
+		return i.CustomCheckInstanceModel
+	})
+}
+
+// fetchChecksAndInstances retrieves checks and their instances from the API.
+// Unless deleteMissing is true, only checks named in fileCheckNames are
+// returned.
+func fetchChecksAndInstances(client *req.Client, org string, fileCheckNames []string, deleteMissing bool) ([]opa.OPACustomCheck, []opa.CheckSetting, error) {
 	apiChecks, err := GetChecks(client, org)
 	if err != nil {
 		logrus.Error("Error getting checks from Insights")
-		return results, err
+		return nil, nil, err
 	}
 	if !deleteMissing {
 		apiChecks = lo.Filter(apiChecks, func(c opa.OPACustomCheck, _ int) bool {
-			return len(lo.Filter(fileChecks, func(fc models.CustomCheckModel, _ int) bool {
-				return fc.CheckName == c.Name
-			})) > 0
+			return lo.Contains(fileCheckNames, c.Name)
 		})
 	}
-	fileChecks = lo.Filter(fileChecks, func(fc models.CustomCheckModel, _ int) bool {
-		return fc.Rego != ""
-	})
-	var apiInstances []opa.CheckSetting
 	// TODO replace with org wide get.
-	for _, check := range apiChecks {
-		newInstances, err := GetInstances(client, org, check.Name)
-		if err != nil {
-			logrus.Error("Error getting instances from Insights")
-			return results, err
-		}
-		apiInstances = append(apiInstances, newInstances...)
+	instancesByCheck, err := workerpool.Map(workerpool.Concurrency(), apiChecks, func(check opa.OPACustomCheck) ([]opa.CheckSetting, error) {
+		return GetInstances(client, org, check.Name)
+	})
+	if err != nil {
+		logrus.Error("Error getting instances from Insights")
+		return nil, nil, err
 	}
-	results = compareChecks(fileChecks, apiChecks, apiInstances)
-	return results, nil
+	return apiChecks, lo.Flatten(instancesByCheck), nil
+}
+
+func fileCheckNames(fileChecks []models.CustomCheckModel) []string {
+	return lo.Map(fileChecks, func(fc models.CustomCheckModel, _ int) string {
+		return fc.CheckName
+	})
 }
 
 func instanceMatchesName(name string) func(opa.CheckSetting, int) bool {
@@ -79,9 +164,16 @@ func instanceMatchesName(name string) func(opa.CheckSetting, int) bool {
 	}
 }
 
+// getMissingChecks returns the owned checks in the API, selected by the name
+// globs of the push filter, that are not in fileChecks.
 func getMissingChecks(apiChecks []opa.OPACustomCheck, fileChecks []models.CustomCheckModel) []models.CustomCheckModel {
+	ownedChecks := lo.Filter(apiChecks, func(c opa.OPACustomCheck, _ int) bool {
+		// labels are only in local files, so checks only in Insights are not
+		// deleted while a label selector is set
+		return ownership.Owns(c.Name) && filter.MatchesRemote(c.Name)
+	})
 	left, _ := lo.Difference(
-		lo.Map(apiChecks, func(c opa.OPACustomCheck, _ int) string {
+		lo.Map(ownedChecks, func(c opa.OPACustomCheck, _ int) string {
 			return c.Name
 		}),
 		lo.Map(fileChecks, func(c models.CustomCheckModel, _ int) string {
@@ -95,6 +187,9 @@ func getMissingChecks(apiChecks []opa.OPACustomCheck, fileChecks []models.Custom
 	return diffChecks
 }
 
+// compareChecks compares fileChecks with the checks and instances in the API.
+// Checks not selected by the push filter are left alone, and only checks
+// owned by the configured owner are deleted.
 func compareChecks(fileChecks []models.CustomCheckModel, apiChecks []opa.OPACustomCheck, apiInstances []opa.CheckSetting) CompareResults {
 	var results CompareResults
 	results.CheckDelete = append(results.CheckDelete, getMissingChecks(apiChecks, fileChecks)...)
@@ -107,6 +202,9 @@ func compareChecks(fileChecks []models.CustomCheckModel, apiChecks []opa.OPACust
 		}
 	}
 
+	fileChecks = lo.Filter(fileChecks, func(fc models.CustomCheckModel, _ int) bool {
+		return filter.Matches(fc.CheckName, fc.Labels)
+	})
 	for _, fileCheck := range fileChecks {
 		found := false
 		for _, check := range apiChecks {
@@ -114,6 +212,7 @@ func compareChecks(fileChecks []models.CustomCheckModel, apiChecks []opa.OPACust
 				found = true
 				if checksDoNotMatch(fileCheck, check) {
 					results.CheckUpdate = append(results.CheckUpdate, fileCheck)
+					results.Diffs = append(results.Diffs, checkDiffs(fileCheck, check)...)
 				}
 				break
 			}
@@ -129,6 +228,7 @@ func compareChecks(fileChecks []models.CustomCheckModel, apiChecks []opa.OPACust
 					found = true
 					if instancesDoNotMatch(fileInstance, instance) {
 						results.InstanceUpdate = append(results.InstanceUpdate, fileInstance)
+						results.Diffs = append(results.Diffs, instanceDiffs(fileInstance, instance)...)
 					}
 					break
 				}
@@ -161,7 +261,7 @@ func getDifferenceInstances(instances []opa.CheckSetting, fileCheck models.Custo
 
 func instancesDoNotMatch(fileInstance models.CustomCheckInstanceModel, apiInstance opa.CheckSetting) bool {
 	// TODO check for changed clusters/run environments
-	return !reflect.DeepEqual(apiInstance.AdditionalData.Parameters, fileInstance.Parameters) ||
+	return !jsonEqual(apiInstance.AdditionalData.Parameters, fileInstance.Parameters) ||
 		targetsNotEqual(apiInstance.Targets, fileInstance.Targets) ||
 		!reflect.DeepEqual(apiInstance.AdditionalData.Output.Category, fileInstance.Output.Category) ||
 		!reflect.DeepEqual(apiInstance.AdditionalData.Output.Remediation, fileInstance.Output.Remediation) ||
@@ -169,6 +269,23 @@ func instancesDoNotMatch(fileInstance models.CustomCheckInstanceModel, apiInstan
 		!reflect.DeepEqual(apiInstance.AdditionalData.Output.Title, fileInstance.Output.Title)
 }
 
+// jsonEqual compares a and b through JSON, so numbers read from YAML compare
+// equal to those returned by the API.
+func jsonEqual(a, b any) bool {
+	normalize := func(v any) any {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var n any
+		if err := json.Unmarshal(b, &n); err != nil {
+			return nil
+		}
+		return n
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
 func checksDoNotMatch(fileCheck models.CustomCheckModel, apiCheck opa.OPACustomCheck) bool {
 	return apiCheck.Rego != fileCheck.Rego ||
 		!reflect.DeepEqual(apiCheck.Category, fileCheck.Output.Category) ||
@@ -178,17 +295,149 @@ func checksDoNotMatch(fileCheck models.CustomCheckModel, apiCheck opa.OPACustomC
 }
 
 func targetsNotEqual(apiTarget []string, fileTarget []models.KubernetesTarget) bool {
-	var fileStringTargets []string
-	for _, target := range fileTarget {
+	fileStringTargets := targetStrings(fileTarget)
+	sort.Strings(apiTarget)
+	return !reflect.DeepEqual(apiTarget, fileStringTargets)
+}
+
+// targetStrings returns the sorted group/kind form of targets, as used by the API.
+func targetStrings(targets []models.KubernetesTarget) []string {
+	var stringTargets []string
+	for _, target := range targets {
 		for _, kind := range target.Kinds {
 			for _, group := range target.APIGroups {
-				fileStringTargets = append(fileStringTargets, fmt.Sprintf("%s/%s", group, kind))
+				stringTargets = append(stringTargets, fmt.Sprintf("%s/%s", group, kind))
 			}
 		}
 	}
-	sort.Strings(fileStringTargets)
-	sort.Strings(apiTarget)
-	return !reflect.DeepEqual(apiTarget, fileStringTargets)
+	sort.Strings(stringTargets)
+	return stringTargets
+}
+
+// targetsFromStrings converts the group/kind targets used by the API to
+// KubernetesTargets, with one target per API group.
+func targetsFromStrings(targets []string) []models.KubernetesTarget {
+	var kubernetesTargets []models.KubernetesTarget
+	groupIndex := map[string]int{}
+	for _, t := range targets {
+		group, kind, _ := strings.Cut(t, "/")
+		i, ok := groupIndex[group]
+		if !ok {
+			i = len(kubernetesTargets)
+			groupIndex[group] = i
+			kubernetesTargets = append(kubernetesTargets, models.KubernetesTarget{APIGroups: []string{group}})
+		}
+		kubernetesTargets[i].Kinds = append(kubernetesTargets[i].Kinds, kind)
+	}
+	return kubernetesTargets
+}
+
+// checkModelFromAPI converts a check and its instances, as returned by the
+// API, to the model that is pushed to the API.
+func checkModelFromAPI(check opa.OPACustomCheck, instances []opa.CheckSetting) models.CustomCheckModel {
+	model := models.CustomCheckModel{
+		CheckName: check.Name,
+		Version:   float32(check.Version),
+		Rego:      check.Rego,
+		Output: models.OutputModel{
+			Title:       check.Title,
+			Severity:    check.Severity,
+			Remediation: check.Remediation,
+			Category:    check.Category,
+		},
+	}
+	for _, instance := range lo.Filter(instances, instanceMatchesName(check.Name)) {
+		model.Instances = append(model.Instances, instanceModelFromAPI(instance))
+	}
+	return model
+}
+
+// instanceModelFromAPI converts an instance, as returned by the API, to the
+// model that is pushed to the API.
+func instanceModelFromAPI(instance opa.CheckSetting) models.CustomCheckInstanceModel {
+	return models.CustomCheckInstanceModel{
+		CheckName:    instance.CheckName,
+		InstanceName: instance.AdditionalData.Name,
+		Targets:      targetsFromStrings(instance.Targets),
+		Parameters:   instance.AdditionalData.Parameters,
+		Output: models.OutputModel{
+			Title:       instance.AdditionalData.Output.Title,
+			Severity:    instance.AdditionalData.Output.Severity,
+			Remediation: instance.AdditionalData.Output.Remediation,
+			Category:    instance.AdditionalData.Output.Category,
+		},
+	}
+}
+
+// instanceDiffContent is the part of an instance that is compared by
+// instancesDoNotMatch.
+type instanceDiffContent struct {
+	Targets    []string           `yaml:"targets"`
+	Parameters map[string]any     `yaml:"parameters"`
+	Output     models.OutputModel `yaml:"output"`
+}
+
+// checkDiffs returns the differences between the rego and output settings of
+// a local check and its counterpart in Insights.
+func checkDiffs(fileCheck models.CustomCheckModel, apiCheck opa.OPACustomCheck) []diff.Diff {
+	diffs := []diff.Diff{{Resource: "OPA policy", Name: fileCheck.CheckName, Format: "rego", Remote: apiCheck.Rego, Local: fileCheck.Rego}}
+	apiMetadata := policyMetadata{
+		Output: models.OutputModel{Title: apiCheck.Title, Severity: apiCheck.Severity, Remediation: apiCheck.Remediation, Category: apiCheck.Category},
+	}
+	fileMetadata := policyMetadata{Output: fileCheck.Output}
+	if !reflect.DeepEqual(apiMetadata, fileMetadata) {
+		outputDiff, err := diff.YAML("OPA policy", fileCheck.CheckName, apiMetadata, fileMetadata)
+		if err != nil {
+			logrus.Warnf("unable to diff output of OPA policy %s: %v", fileCheck.CheckName, err)
+			return diffs
+		}
+		diffs = append(diffs, outputDiff)
+	}
+	return diffs
+}
+
+// instanceDiffs returns the differences between a local instance and its
+// counterpart in Insights.
+func instanceDiffs(fileInstance models.CustomCheckInstanceModel, apiInstance opa.CheckSetting) []diff.Diff {
+	apiTargets := append([]string(nil), apiInstance.Targets...)
+	sort.Strings(apiTargets)
+	remote := instanceDiffContent{
+		Targets:    apiTargets,
+		Parameters: apiInstance.AdditionalData.Parameters,
+		Output: models.OutputModel{
+			Title:       apiInstance.AdditionalData.Output.Title,
+			Severity:    apiInstance.AdditionalData.Output.Severity,
+			Remediation: apiInstance.AdditionalData.Output.Remediation,
+			Category:    apiInstance.AdditionalData.Output.Category,
+		},
+	}
+	local := instanceDiffContent{
+		Targets:    targetStrings(fileInstance.Targets),
+		Parameters: fileInstance.Parameters,
+		Output:     fileInstance.Output,
+	}
+	name := fileInstance.CheckName + "/" + fileInstance.InstanceName
+	instanceDiff, err := diff.YAML("OPA policy instance", name, remote, local)
+	if err != nil {
+		logrus.Warnf("unable to diff OPA policy instance %s: %v", name, err)
+		return nil
+	}
+	return []diff.Diff{instanceDiff}
+}
+
+// policySettingsFileName is the name of the file holding the output settings
+// of an OPA policy, alongside its policy.rego.
+const policySettingsFileName = ".policy-settings.yaml"
+
+// instancesDirName is the name of the directory, alongside the policy.rego of
+// an OPA policy, holding a YAML file per instance of that policy. Other YAML
+// files in a policy directory, such as validation fixtures, are not pushed.
+const instancesDirName = "instances"
+
+// policyMetadata holds the settings of an OPA policy other than its rego.
+type policyMetadata struct {
+	Output models.OutputModel `yaml:"output"`
+	Labels map[string]string  `yaml:"labels,omitempty"`
 }
 
 func getChecksFromFiles(files map[string][]string) ([]models.CustomCheckModel, error) {
@@ -197,7 +446,7 @@ func getChecksFromFiles(files map[string][]string) ([]models.CustomCheckModel, e
 		var check models.CustomCheckModel
 		check.Version = 2.0
 		for _, filePath := range checkFiles {
-			fileContents, err := os.ReadFile(filePath)
+			fileContents, err := overlay.ReadFile(filePath)
 			if err != nil {
 				logrus.Error(err, "Error reading file", filePath)
 				return nil, err
@@ -208,6 +457,34 @@ func getChecksFromFiles(files map[string][]string) ([]models.CustomCheckModel, e
 				check.Rego = string(fileContents)
 				continue
 			}
+			if extension != ".yaml" {
+				continue
+			}
+			if filepath.Base(filePath) == policySettingsFileName {
+				logrus.Debugf("using content of file %s as settings for OPA policy %s\n", filePath, checkName)
+				var metadata policyMetadata
+				err = yaml.Unmarshal(fileContents, &metadata)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing OPA policy settings %s: %w", filePath, err)
+				}
+				check.Output = metadata.Output
+				check.Labels = metadata.Labels
+				continue
+			}
+			if filepath.Base(filepath.Dir(filePath)) != instancesDirName {
+				logrus.Debugf("ignoring file %s of OPA policy %s, as it is not in the %s directory\n", filePath, checkName, instancesDirName)
+				continue
+			}
+			baseName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+			logrus.Debugf("using content of file %s as instance %s of OPA policy %s\n", filePath, baseName, checkName)
+			var instance models.CustomCheckInstanceModel
+			err = yaml.Unmarshal(fileContents, &instance)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing OPA policy instance %s: %w", filePath, err)
+			}
+			instance.CheckName = checkName
+			instance.InstanceName = baseName
+			check.Instances = append(check.Instances, instance)
 		}
 		check.CheckName = checkName
 		logrus.Debugf("processed files %s as v%.1f OPA policy %s\n", checkFiles, check.Version, check.CheckName)
@@ -21,11 +21,17 @@ import (
 	"os"
 	"strings"
 
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/diff"
 	"github.com/fairwindsops/insights-cli/pkg/directory"
 	"github.com/fairwindsops/insights-cli/pkg/models"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
 	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
 	opaPlugin "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/opa"
 	"github.com/imroc/req/v3"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
@@ -48,8 +54,7 @@ func GetChecks(client *req.Client, org string) ([]opaPlugin.OPACustomCheck, erro
 	}
 	var checks []opaPlugin.OPACustomCheck
 	if resp.IsErrorState() {
-		logrus.Errorf("GetChecks: invalid response code: %s %v", string(resp.Bytes()), resp.StatusCode)
-		return nil, errors.New("GetChecks: invalid response code")
+		return nil, fmt.Errorf("GetChecks: invalid response code %d: %s", resp.StatusCode, string(resp.Bytes()))
 	}
 	err = resp.Unmarshal(&checks)
 	if err != nil {
@@ -77,6 +82,16 @@ func GetInstances(client *req.Client, org, checkName string) ([]opaPlugin.CheckS
 	return instances, nil
 }
 
+// DetectRegoVersion returns v1 if rego parses as Rego v1, and otherwise v0.
+// The API does not return the version a check was pushed with.
+func DetectRegoVersion(rego string) string {
+	_, err := ast.ParseModuleWithOpts("policy.rego", rego, ast.ParserOptions{RegoVersion: ast.RegoV1})
+	if err == nil {
+		return "v1"
+	}
+	return "v0"
+}
+
 // DeleteCheck deletes an OPA Check from Fairwinds Insights
 func DeleteCheck(client *req.Client, check models.CustomCheckModel, org string) error {
 	url := fmt.Sprintf(opaCheckURLFormat, org, check.CheckName)
@@ -95,13 +110,18 @@ type PutCheckRequest struct {
 	Rego, Description string
 	Disabled          *bool
 	RegoVersion       string
+	// OutputModel holds the action item settings read from
+	// .policy-settings.yaml, sent as the same fields GetChecks returns.
+	models.OutputModel
 }
 
 // PutCheck upserts an OPA Check to Fairwinds Insights
 func PutCheck(client *req.Client, check models.CustomCheckModel, org string, pushRegoVersion string) error {
 	url := fmt.Sprintf(opaPutCheckURLFormat, org, check.CheckName, check.Version)
-	body := PutCheckRequest{Rego: check.Rego, Description: check.Description, Disabled: check.Disabled}
-	if pushRegoVersion != "" {
+	body := PutCheckRequest{Rego: check.Rego, Description: check.Description, Disabled: check.Disabled, OutputModel: check.Output}
+	if check.RegoVersion != "" {
+		body.RegoVersion = check.RegoVersion
+	} else if pushRegoVersion != "" {
 		body.RegoVersion = pushRegoVersion
 	} else {
 		body.RegoVersion = "v0"
@@ -149,81 +169,225 @@ func PutInstance(client *req.Client, instance models.CustomCheckInstanceModel, o
 	return nil
 }
 
-// PushOPAChecks pushes OPA checks to Insights.
-func PushOPAChecks(client *req.Client, pushDir, org string, deleteMissing, dryRun bool, pushRegoVersion string) error {
-	logrus.Debugln("Pushing OPA policies")
+// Plan holds the OPA check and instance changes that will be made by
+// ApplyPlan, and a fingerprint of the checks and instances in Insights at the
+// time the plan was built.
+type Plan struct {
+	Changes           CompareResults `json:"changes"`
+	RegoVersion       string         `json:"regoVersion"`
+	DeleteMissing     bool           `json:"deleteMissing"`
+	FileCheckNames    []string       `json:"fileCheckNames"`
+	RemoteFingerprint string         `json:"remoteFingerprint"`
+}
+
+// BuildPlan compares the OPA checks in pushDir with those in Insights, and
+// returns the changes needed to make Insights match pushDir.
+func BuildPlan(client *req.Client, pushDir, org string, deleteMissing bool, pushRegoVersion string) (*Plan, error) {
 	_, err := os.Stat(pushDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	files, err := directory.ScanOPAFolder(pushDir)
+	files, err := overlay.ScanFolder(pushDir, directory.ScanOPAFolder)
 	if err != nil {
-		return fmt.Errorf("error scanning directory: %w", err)
+		return nil, fmt.Errorf("error scanning directory: %w", err)
 	}
 	fileChecks, err := getChecksFromFiles(files)
 	if err != nil {
-		return fmt.Errorf("error Reading checks from files: %w", err)
+		return nil, fmt.Errorf("error Reading checks from files: %w", err)
+	}
+	return buildPlan(client, org, fileChecks, deleteMissing, pushRegoVersion)
+}
+
+// buildPlan compares fileChecks with the checks in Insights, and returns the
+// changes needed to make Insights match them.
+func buildPlan(client *req.Client, org string, fileChecks []models.CustomCheckModel, deleteMissing bool, pushRegoVersion string) (*Plan, error) {
+	apiChecks, apiInstances, err := fetchChecksAndInstances(client, org, fileCheckNames(fileChecks), deleteMissing)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := utils.Fingerprint([]any{apiChecks, apiInstances})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint OPA policies: %w", err)
+	}
+	plan := Plan{
+		RegoVersion:       pushRegoVersion,
+		DeleteMissing:     deleteMissing,
+		FileCheckNames:    fileCheckNames(fileChecks),
+		RemoteFingerprint: fingerprint,
+	}
+	fileChecks = lo.Filter(fileChecks, func(fc models.CustomCheckModel, _ int) bool {
+		return fc.Rego != ""
+	})
+	plan.Changes = compareChecks(fileChecks, apiChecks, apiInstances)
+	return &plan, nil
+}
+
+// BuildRestorePlan returns the changes needed to make the OPA checks and
+// instances in Insights match those given, which were previously retrieved
+// with GetChecks and GetInstances. Checks that are not given are deleted.
+// Checks are pushed with their version in regoVersions, or pushRegoVersion if
+// it does not have one.
+func BuildRestorePlan(client *req.Client, org string, checks []opaPlugin.OPACustomCheck, instances []opaPlugin.CheckSetting, regoVersions map[string]string, pushRegoVersion string) (*Plan, error) {
+	desiredChecks := lo.Map(checks, func(c opaPlugin.OPACustomCheck, _ int) models.CustomCheckModel {
+		model := checkModelFromAPI(c, instances)
+		model.RegoVersion = regoVersions[c.Name]
+		return model
+	})
+	apiChecks, apiInstances, err := fetchChecksAndInstances(client, org, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := utils.Fingerprint([]any{apiChecks, apiInstances})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint OPA policies: %w", err)
+	}
+	return &Plan{
+		Changes:           compareChecks(desiredChecks, apiChecks, apiInstances),
+		RegoVersion:       pushRegoVersion,
+		DeleteMissing:     true,
+		FileCheckNames:    fileCheckNames(desiredChecks),
+		RemoteFingerprint: fingerprint,
+	}, nil
+}
+
+// RemoteFingerprint returns the fingerprint of the OPA policies in Insights
+// covered by the plan, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	apiChecks, apiInstances, err := fetchChecksAndInstances(client, org, plan.FileCheckNames, plan.DeleteMissing)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := utils.Fingerprint([]any{apiChecks, apiInstances})
+	if err != nil {
+		return "", fmt.Errorf("unable to fingerprint OPA policies: %w", err)
 	}
-	results, err := CompareChecks(client, pushDir, org, fileChecks, deleteMissing)
+	return fingerprint, nil
+}
+
+// VerifyPlan returns an error if the OPA checks or instances in Insights have
+// changed since the plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
 	if err != nil {
 		return err
 	}
-	for _, instance := range results.InstanceDelete {
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("OPA policies in Insights have changed since the plan was created")
+	}
+	return nil
+}
+
+// ApplyPlan makes the changes described by the plan in Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	return applyResults(client, org, plan.Changes, dryRun, plan.RegoVersion)
+}
+
+// applyResults deletes instances before checks, and adds checks before
+// their instances. The changes within each step are made concurrently, and a
+// step that fails stops the steps after it.
+func applyResults(client *req.Client, org string, results CompareResults, dryRun bool, pushRegoVersion string) error {
+	limit := workerpool.Concurrency()
+	if dryRun {
+		limit = 1
+	}
+	err := deletion.Confirm("OPA policies and instances", deletedNames(results), dryRun)
+	if err != nil {
+		return err
+	}
+	err = workerpool.Run(limit, results.InstanceDelete, func(instance models.CustomCheckInstanceModel) error {
 		logrus.Infof("Deleting instance: %s for OPA policy %s", instance.InstanceName, instance.CheckName)
-		if !dryRun {
-			err := DeleteInstance(client, instance, org)
-			if err != nil {
-				return err
-			}
+		if dryRun {
+			return nil
+		}
+		err := DeleteInstance(client, instance, org)
+		if err != nil {
+			return fmt.Errorf("error deleting instance %s: %w", instance.InstanceName, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	for _, check := range results.CheckDelete {
+	err = workerpool.Run(limit, results.CheckDelete, func(check models.CustomCheckModel) error {
 		logrus.Infof("Deleting OPA policy: %s", check.CheckName)
-		if !dryRun {
-			err := DeleteCheck(client, check, org)
-			if err != nil {
-				return err
-			}
+		if dryRun {
+			return nil
+		}
+		err := DeleteCheck(client, check, org)
+		if err != nil {
+			return fmt.Errorf("error deleting check %s: %w", check.CheckName, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	for _, check := range results.CheckInsert {
+	err = workerpool.Run(limit, results.CheckInsert, func(check models.CustomCheckModel) error {
 		logrus.Infof("Adding v%.0f OPA policy: %s", check.Version, check.CheckName)
-		if !dryRun {
-			err := PutCheck(client, check, org, pushRegoVersion)
-			if err != nil {
-				return err
-			}
+		if dryRun {
+			return nil
+		}
+		err := PutCheck(client, check, org, pushRegoVersion)
+		if err != nil {
+			return fmt.Errorf("error adding check %s: %w", check.CheckName, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	for _, check := range results.CheckUpdate {
+	err = workerpool.Run(limit, results.CheckUpdate, func(check models.CustomCheckModel) error {
 		logrus.Infof("Updating v%.0f OPA policy: %s", check.Version, check.CheckName)
-		if !dryRun {
-			err := PutCheck(client, check, org, pushRegoVersion)
-			if err != nil {
-				return err
-			}
+		if dryRun {
+			return diff.Print(os.Stdout, diff.ForName(results.Diffs, check.CheckName))
 		}
+		err := PutCheck(client, check, org, pushRegoVersion)
+		if err != nil {
+			return fmt.Errorf("error updating check %s: %w", check.CheckName, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	for _, instance := range results.InstanceInsert {
+	err = workerpool.Run(limit, results.InstanceInsert, func(instance models.CustomCheckInstanceModel) error {
 		logrus.Infof("Adding instance: %s for OPA policy %s", instance.InstanceName, instance.CheckName)
-		if !dryRun {
-			err := PutInstance(client, instance, org)
-			if err != nil {
-				return err
-			}
+		if dryRun {
+			return nil
 		}
+		err := PutInstance(client, instance, org)
+		if err != nil {
+			return fmt.Errorf("error adding instance %s: %w", instance.InstanceName, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	for _, instance := range results.InstanceUpdate {
+	return workerpool.Run(limit, results.InstanceUpdate, func(instance models.CustomCheckInstanceModel) error {
 		logrus.Infof("Updating instance: %s for OPA policy %s", instance.InstanceName, instance.CheckName)
-		if !dryRun {
-			err := PutInstance(client, instance, org)
-			if err != nil {
-				return err
-			}
+		if dryRun {
+			return diff.Print(os.Stdout, diff.ForName(results.Diffs, instance.CheckName+"/"+instance.InstanceName))
+		}
+		err := PutInstance(client, instance, org)
+		if err != nil {
+			return fmt.Errorf("error updating instance %s: %w", instance.InstanceName, err)
+		}
+		return nil
+	})
+}
+
+// deletedNames returns the names of the checks deleted by results, and of
+// the instances deleted from checks that are kept.
+func deletedNames(results CompareResults) []string {
+	deletedChecks := fileCheckNames(results.CheckDelete)
+	names := append([]string{}, deletedChecks...)
+	for _, instance := range results.InstanceDelete {
+		if !lo.Contains(deletedChecks, instance.CheckName) {
+			names = append(names, instance.CheckName+"/"+instance.InstanceName)
 		}
 	}
-	logrus.Debugln("Done pushing OPA policies")
-	return nil
+	return names
 }
 
 // PushExternalOPAChecks pushes external OPA checks to Insights.
@@ -254,63 +418,13 @@ func PushExternalOPAChecks(client *req.Client, filePath, org string, headers []s
 		return fmt.Errorf("error getting remote checks: %w", err)
 	}
 
-	results, err := CompareChecks(client, filePath, org, checks, deleteMissing)
+	plan, err := buildPlan(client, org, checks, deleteMissing, pushRegoVersion)
 	if err != nil {
 		return fmt.Errorf("error comparing checks: %w", err)
 	}
-	for _, instance := range results.InstanceDelete {
-		logrus.Infof("Deleting instance: %s for OPA policy %s", instance.InstanceName, instance.CheckName)
-		if !dryRun {
-			err := DeleteInstance(client, instance, org)
-			if err != nil {
-				return fmt.Errorf("error deleting instance: %w", err)
-			}
-		}
-	}
-	for _, check := range results.CheckDelete {
-		logrus.Infof("Deleting OPA policy: %s", check.CheckName)
-		if !dryRun {
-			err := DeleteCheck(client, check, org)
-			if err != nil {
-				return fmt.Errorf("error deleting check: %w", err)
-			}
-		}
-	}
-	for _, check := range results.CheckInsert {
-		logrus.Infof("Adding v%.0f OPA policy: %s", check.Version, check.CheckName)
-		if !dryRun {
-			err := PutCheck(client, check, org, pushRegoVersion)
-			if err != nil {
-				return fmt.Errorf("error adding check: %w", err)
-			}
-		}
-	}
-	for _, check := range results.CheckUpdate {
-		logrus.Infof("Updating v%.0f OPA policy: %s", check.Version, check.CheckName)
-		if !dryRun {
-			err := PutCheck(client, check, org, pushRegoVersion)
-			if err != nil {
-				return fmt.Errorf("error updating check: %w", err)
-			}
-		}
-	}
-	for _, instance := range results.InstanceInsert {
-		logrus.Infof("Adding instance: %s for OPA policy %s", instance.InstanceName, instance.CheckName)
-		if !dryRun {
-			err := PutInstance(client, instance, org)
-			if err != nil {
-				return fmt.Errorf("error adding instance: %w", err)
-			}
-		}
-	}
-	for _, instance := range results.InstanceUpdate {
-		logrus.Infof("Updating instance: %s for OPA policy %s", instance.InstanceName, instance.CheckName)
-		if !dryRun {
-			err := PutInstance(client, instance, org)
-			if err != nil {
-				return fmt.Errorf("error updating instance: %w", err)
-			}
-		}
+	err = ApplyPlan(client, org, *plan, dryRun)
+	if err != nil {
+		return err
 	}
 	logrus.Debugln("Done pushing external OPA policies")
 	return nil
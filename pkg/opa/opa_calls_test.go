@@ -9,6 +9,7 @@ import (
 
 	"github.com/imroc/req/v3"
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,6 +44,23 @@ func TestGetExternalChecksFromFileWrongCreds(t *testing.T) {
 	assert.Len(t, c, 0)
 }
 
+func TestGetChecksForbidden(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, err := w.Write([]byte("forbidden"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	_, err := GetChecks(req.C().SetBaseURL(server.URL), "acme-co")
+	assert.EqualError(t, err, "GetChecks: invalid response code 403: forbidden")
+	for _, entry := range hook.AllEntries() {
+		assert.Greater(t, entry.Level, logrus.ErrorLevel, "a check the token cannot read is not logged as an error")
+	}
+}
+
 func TestGetExternalChecksFromFile(t *testing.T) {
 	externalServer := httptest.NewServer(http.HandlerFunc(simpleExternalHandler))
 	defer externalServer.Close()
@@ -15,12 +15,18 @@
 package opa
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/fairwindsops/insights-plugins/plugins/opa/pkg/opa"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/fairwindsops/insights-cli/pkg/directory"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
 	"github.com/fairwindsops/insights-cli/pkg/models"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
 )
 
 func TestCompareCheck(t *testing.T) {
@@ -97,6 +103,27 @@ func TestCompareCheck(t *testing.T) {
 	// TODO implement checks for updates, deletes, and instances
 }
 
+func TestCompareCheckDiffs(t *testing.T) {
+	checks := []models.CustomCheckModel{
+		{
+			CheckName: "Check1",
+			Rego:      "package fairwinds\nallow = false\n",
+		},
+	}
+	apiChecks := []opa.OPACustomCheck{
+		{
+			Name: "Check1",
+			Rego: "package fairwinds\nallow = true\n",
+		},
+	}
+	results := compareChecks(checks, apiChecks, nil)
+	assert.Equal(t, 1, len(results.CheckUpdate))
+	assert.Equal(t, 1, len(results.Diffs))
+	unified, err := results.Diffs[0].Unified()
+	assert.NoError(t, err)
+	assert.Contains(t, unified, "-allow = true\n+allow = false\n")
+}
+
 func TestTargetsNotEqual(t *testing.T) {
 	apiTarget := []string{
 		"core/type1",
@@ -124,3 +151,87 @@ func TestTargetsNotEqual(t *testing.T) {
 	}
 	assert.True(t, targetsNotEqual(apiTarget, fileTargets))
 }
+
+func TestCheckModelFromAPI(t *testing.T) {
+	apiChecks := []opa.OPACustomCheck{
+		{
+			Name:    "Check1",
+			Version: 2,
+			Rego:    "package fairwinds",
+		},
+	}
+	apiInstances := []opa.CheckSetting{
+		{
+			CheckName: "Check1",
+			Targets:   []string{"apps/Deployment", "/Pod"},
+		},
+	}
+	apiInstances[0].AdditionalData.Name = "instance1"
+	apiInstances[0].AdditionalData.Parameters = map[string]any{"labels": []any{"team"}}
+	check := checkModelFromAPI(apiChecks[0], apiInstances)
+	assert.Equal(t, "Check1", check.CheckName)
+	assert.Equal(t, 1, len(check.Instances))
+	assert.Equal(t, "instance1", check.Instances[0].InstanceName)
+	results := compareChecks([]models.CustomCheckModel{check}, apiChecks, apiInstances)
+	assert.Equal(t, CompareResults{}, results)
+}
+
+func TestCompareChecksOwnership(t *testing.T) {
+	ownership.SetOwner("acme/policies")
+	ownership.SetOwned([]string{"owned"})
+	defer ownership.SetOwner("")
+	defer ownership.SetOwned(nil)
+	apiChecks := []opa.OPACustomCheck{{Name: "owned"}, {Name: "other"}, {Name: "claimed"}}
+	checks := []models.CustomCheckModel{{CheckName: "claimed", Rego: "package claimed"}}
+	results := compareChecks(checks, apiChecks, nil)
+	assert.Equal(t, []string{"owned"}, lo.Map(results.CheckDelete, func(c models.CustomCheckModel, _ int) string { return c.CheckName }),
+		"checks not recorded as pushed with the owner are not deleted")
+	assert.Equal(t, []string{"claimed"}, lo.Map(results.CheckUpdate, func(c models.CustomCheckModel, _ int) string { return c.CheckName }))
+}
+
+func TestCompareChecksFilter(t *testing.T) {
+	f, err := filter.New([]string{"team-*"}, nil, "")
+	assert.NoError(t, err)
+	filter.Set(f)
+	defer filter.Set(filter.Filter{})
+	apiChecks := []opa.OPACustomCheck{{Name: "team-old"}, {Name: "other-old"}, {Name: "team-a", Rego: "old"}}
+	checks := []models.CustomCheckModel{{CheckName: "team-a", Rego: "new"}, {CheckName: "other-new", Rego: "new"}}
+	results := compareChecks(checks, apiChecks, nil)
+	assert.Equal(t, []string{"team-old"}, lo.Map(results.CheckDelete, func(c models.CustomCheckModel, _ int) string { return c.CheckName }))
+	assert.Equal(t, []string{"team-a"}, lo.Map(results.CheckUpdate, func(c models.CustomCheckModel, _ int) string { return c.CheckName }))
+	assert.Empty(t, results.CheckInsert)
+
+	f, err = filter.New(nil, nil, "team=web")
+	assert.NoError(t, err)
+	filter.Set(f)
+	checks = []models.CustomCheckModel{{CheckName: "team-a", Rego: "new", Labels: map[string]string{"team": "api"}}}
+	results = compareChecks(checks, apiChecks, nil)
+	assert.Empty(t, results.CheckDelete, "labels of checks only in Insights are unknown, so none are deleted with a selector")
+	assert.Empty(t, results.CheckUpdate)
+}
+
+func TestGetChecksFromFilesSkipsFixtures(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"needs-label/policy.rego":                      check,
+		"needs-label/.policy-settings.yaml":            "output:\n  title: Missing label\n",
+		"needs-label/instances/team.yaml":              "parameters:\n  label: team\n",
+		"needs-label/policy.yaml":                      "kind: Deployment\n",
+		"needs-label/policy.pod.success.yaml":          "kind: Pod\n",
+		"needs-label/policy.pod.failure.yaml":          "kind: Pod\n",
+		"needs-label/policy.pod.failure.expected.yaml": "- title: Missing label\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	scanned, err := directory.ScanOPAFolder(dir)
+	assert.NoError(t, err)
+	checks, err := getChecksFromFiles(scanned)
+	assert.NoError(t, err)
+	assert.Len(t, checks, 1)
+	assert.Equal(t, "needs-label", checks[0].CheckName)
+	assert.Equal(t, check, checks[0].Rego)
+	assert.Equal(t, "Missing label", *checks[0].Output.Title)
+	assert.Equal(t, []string{"team"}, lo.Map(checks[0].Instances, func(i models.CustomCheckInstanceModel, _ int) string { return i.InstanceName }))
+}
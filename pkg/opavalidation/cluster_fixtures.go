@@ -0,0 +1,186 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opavalidation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ClusterFixturesSuffix is appended to the base file name of an OPA policy to
+// name the directory of its own cluster fixtures, in batch mode.
+const ClusterFixturesSuffix = ".cluster-fixtures"
+
+// ClusterFixtures are Kubernetes objects returned by the kubernetes() rego
+// function during validation, indexed by lower-case kind and then namespace.
+// Cluster-scoped objects have an empty namespace.
+type ClusterFixtures map[string]map[string][]map[string]any
+
+// LoadClusterFixtures reads the Kubernetes manifests in the .yaml and .yml
+// files of dirs and their sub-directories. A file may contain multiple
+// manifests separated by ---, and the items of a List are loaded as objects.
+func LoadClusterFixtures(dirs ...string) (ClusterFixtures, error) {
+	fixtures := ClusterFixtures{}
+	for _, dir := range dirs {
+		var fileNames []string
+		for _, ext := range []string{".yaml", ".yml"} {
+			files, err := FindFilesWithExtension(dir, ext)
+			if err != nil {
+				return nil, fmt.Errorf("unable to list cluster fixtures in %s: %v", dir, err)
+			}
+			fileNames = append(fileNames, files...)
+		}
+		sort.Strings(fileNames)
+		for _, fileName := range fileNames {
+			err := fixtures.addFile(fileName)
+			if err != nil {
+				return nil, fmt.Errorf("error reading cluster fixtures %s: %v", fileName, err)
+			}
+		}
+	}
+	return fixtures, nil
+}
+
+// addFile adds the Kubernetes objects of the manifests in fileName.
+func (f ClusterFixtures) addFile(fileName string) error {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var object map[string]any
+		err := decoder.Decode(&object)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if object == nil {
+			continue // an empty document
+		}
+		err = f.add(object)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// add adds a Kubernetes object, or the items of a List.
+func (f ClusterFixtures) add(object map[string]any) error {
+	kind, _ := object["kind"].(string)
+	if kind == "" {
+		return errors.New("a manifest has no kind")
+	}
+	if items, ok := object["items"].([]any); ok && strings.HasSuffix(kind, "List") {
+		for _, item := range items {
+			itemObject, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("an item of a %s is not a Kubernetes object", kind)
+			}
+			err := f.add(itemObject)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	namespace, _, _ := unstructured.NestedString(object, "metadata", "namespace")
+	kind = strings.ToLower(kind)
+	if f[kind] == nil {
+		f[kind] = map[string][]map[string]any{}
+	}
+	f[kind][namespace] = append(f[kind][namespace], object)
+	return nil
+}
+
+// Objects returns the objects of kind in namespace, or in all namespaces if
+// namespace is empty. The kind is not case-sensitive.
+func (f ClusterFixtures) Objects(kind, namespace string) []map[string]any {
+	byNamespace := f[strings.ToLower(kind)]
+	if namespace != "" {
+		return append([]map[string]any{}, byNamespace[namespace]...)
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	objects := []map[string]any{}
+	for _, ns := range namespaces {
+		objects = append(objects, byNamespace[ns]...)
+	}
+	return objects
+}
+
+// kubernetesFunction returns the implementation of the kubernetes(kind,
+// namespace) rego function, which returns objects from clusterFixtures.
+// Without cluster fixtures, it returns no Kubernetes objects.
+func kubernetesFunction(clusterFixtures ClusterFixtures) rego.Builtin2 {
+	return func(_ rego.BuiltinContext, kindTerm *ast.Term, namespaceTerm *ast.Term) (*ast.Term, error) {
+		if clusterFixtures == nil {
+			logrus.Warnln("NOTE: The rego kubernetes function does not return data when validating OPA policies without cluster fixtures. See the --cluster-fixtures option.")
+			returnData, err := ast.InterfaceToValue([]string{"the rego kubernetes function currently does not return data when validating OPA policies"})
+			if err != nil {
+				return nil, err
+			}
+			return ast.NewTerm(returnData), nil
+		}
+		var kind, namespace string
+		if err := ast.As(kindTerm.Value, &kind); err != nil {
+			return nil, fmt.Errorf("kubernetes function kind: %v", err)
+		}
+		if err := ast.As(namespaceTerm.Value, &namespace); err != nil {
+			return nil, fmt.Errorf("kubernetes function namespace: %v", err)
+		}
+		returnData, err := ast.InterfaceToValue(clusterFixtures.Objects(kind, namespace))
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewTerm(returnData), nil
+	}
+}
+
+// clusterFixturesForPolicy loads the cluster fixtures in clusterFixturesDir,
+// if it is not empty, and in the cluster fixtures directory of the OPA policy
+// in regoFileName, if it exists. It returns nil if there are neither.
+func clusterFixturesForPolicy(regoFileName, clusterFixturesDir string) (ClusterFixtures, error) {
+	var dirs []string
+	if clusterFixturesDir != "" {
+		dirs = append(dirs, clusterFixturesDir)
+	}
+	dir := strings.TrimSuffix(regoFileName, filepath.Ext(regoFileName)) + ClusterFixturesSuffix
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		logrus.Debugf("Using cluster fixtures in %s for policy %s", dir, regoFileName)
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+	return LoadClusterFixtures(dirs...)
+}
@@ -0,0 +1,45 @@
+package opavalidation
+
+import (
+	"testing"
+
+	fwrego "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/rego"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadClusterFixtures(t *testing.T) {
+	fixtures, err := LoadClusterFixtures("testdata/cluster-fixtures")
+	assert.NoError(t, err)
+	names := func(objects []map[string]any) []string {
+		var names []string
+		for _, o := range objects {
+			names = append(names, o["metadata"].(map[string]any)["name"].(string))
+		}
+		return names
+	}
+	assert.Equal(t, []string{"myapp"}, names(fixtures.Objects("PodDisruptionBudget", "default")))
+	assert.Equal(t, []string{"myapp", "otherapp"}, names(fixtures.Objects("poddisruptionbudget", "")))
+	assert.Equal(t, []string{"default", "other"}, names(fixtures.Objects("Namespace", "")))
+	assert.Empty(t, fixtures.Objects("PodDisruptionBudget", "missing"))
+	assert.NotNil(t, fixtures.Objects("Service", ""))
+
+	_, err = LoadClusterFixtures("testdata/missing")
+	assert.Error(t, err)
+}
+
+func TestRunWithClusterFixtures(t *testing.T) {
+	opts := ExpectActionItemOptions{SuccessFileExtension: ".success.yaml", FailureFileExtension: ".failure.yaml"}
+	fixtures, err := LoadClusterFixtures("testdata/cluster-fixtures")
+	assert.NoError(t, err)
+	ais, err := Run("v0", "testdata/pdbrequired.rego", "testdata/deployment-myapp.yaml", opts, fwrego.InsightsInfo{}, "", "", fixtures)
+	assert.NoError(t, err)
+	assert.Len(t, ais, 0)
+
+	// There is no PodDisruptionBudget for myapp in the other namespace.
+	opts.Default = true
+	ais, err = Run("v0", "testdata/pdbrequired.rego", "testdata/deployment-myapp.yaml", opts, fwrego.InsightsInfo{}, "other", "", fixtures)
+	assert.NoError(t, err)
+	if assert.Len(t, ais, 1) {
+		assert.Equal(t, "PodDisruptionBudget is missing", ais[0].Title)
+	}
+}
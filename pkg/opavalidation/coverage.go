@@ -0,0 +1,285 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opavalidation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/cover"
+)
+
+// Formats of coverage files.
+const (
+	CoverageFormatLCOV = "lcov"
+	CoverageFormatJSON = "json"
+)
+
+// CoverageFormats lists the supported coverage file formats.
+var CoverageFormats = []string{CoverageFormatLCOV, CoverageFormatJSON}
+
+// policyModuleName is the name of the module OPA policies are loaded as, and
+// so the file OPA attributes their coverage to.
+const policyModuleName = "fairwinds"
+
+// policyCoverage is the coverage of an OPA policy, combined across every
+// Kubernetes manifest it is validated with.
+type policyCoverage struct {
+	module *ast.Module
+	tracer *cover.Cover
+}
+
+var (
+	coverageMu sync.Mutex
+	// coverage is keyed by OPA policy file name, and is nil unless StartCoverage was called.
+	coverage map[string]*policyCoverage
+)
+
+type coverageTracerKey struct{}
+
+// StartCoverage starts recording which lines of OPA policies are evaluated
+// by Run and RunBatch.
+func StartCoverage() {
+	coverageMu.Lock()
+	defer coverageMu.Unlock()
+	coverage = map[string]*policyCoverage{}
+}
+
+// withCoverageTracer returns a context carrying the coverage tracer of the
+// OPA policy in regoFileName, if coverage is being recorded. A policy that
+// does not parse is not traced; evaluating it reports the error.
+func withCoverageTracer(ctx context.Context, regoFileName, regoContent, regoVersion string) context.Context {
+	coverageMu.Lock()
+	defer coverageMu.Unlock()
+	if coverage == nil {
+		return ctx
+	}
+	pc, ok := coverage[regoFileName]
+	if !ok {
+		module, err := ast.ParseModuleWithOpts(policyModuleName, regoContent, ast.ParserOptions{RegoVersion: astRegoVersion(regoVersion)})
+		if err != nil {
+			return ctx
+		}
+		pc = &policyCoverage{module: module, tracer: cover.New()}
+		coverage[regoFileName] = pc
+	}
+	return context.WithValue(ctx, coverageTracerKey{}, pc.tracer)
+}
+
+// coverageTracerFrom returns the coverage tracer carried by ctx, if any.
+func coverageTracerFrom(ctx context.Context) (*cover.Cover, bool) {
+	tracer, ok := ctx.Value(coverageTracerKey{}).(*cover.Cover)
+	return tracer, ok
+}
+
+// astRegoVersion returns the OPA rego version for a --rego-version value.
+func astRegoVersion(regoVersion string) ast.RegoVersion {
+	if regoVersion == "v1" {
+		return ast.RegoV1
+	}
+	return ast.RegoV0
+}
+
+// LineRange is a range of lines of an OPA policy, inclusive.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return fmt.Sprint(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// PolicyCoverage is the coverage of one OPA policy.
+type PolicyCoverage struct {
+	File string `json:"file"`
+	// Coverage is the percentage of lines that were evaluated.
+	Coverage        float64     `json:"coverage"`
+	CoveredLines    int         `json:"coveredLines"`
+	NotCoveredLines int         `json:"notCoveredLines"`
+	Covered         []LineRange `json:"covered,omitempty"`
+	NotCovered      []LineRange `json:"notCovered,omitempty"`
+}
+
+// CoverageReport is the coverage of the OPA policies validated since
+// StartCoverage was called.
+type CoverageReport struct {
+	Policies        []PolicyCoverage `json:"policies"`
+	Coverage        float64          `json:"coverage"`
+	CoveredLines    int              `json:"coveredLines"`
+	NotCoveredLines int              `json:"notCoveredLines"`
+}
+
+// Coverage returns the coverage recorded since StartCoverage was called,
+// with policies sorted by file name.
+func Coverage() CoverageReport {
+	coverageMu.Lock()
+	defer coverageMu.Unlock()
+	var r CoverageReport
+	regoFileNames := make([]string, 0, len(coverage))
+	for regoFileName := range coverage {
+		regoFileNames = append(regoFileNames, regoFileName)
+	}
+	sort.Strings(regoFileNames)
+	for _, regoFileName := range regoFileNames {
+		pc := coverage[regoFileName]
+		fr := pc.tracer.Report(map[string]*ast.Module{policyModuleName: pc.module}).Files[policyModuleName]
+		p := PolicyCoverage{File: regoFileName}
+		if fr != nil {
+			p.Coverage = fr.Coverage
+			p.CoveredLines = fr.CoveredLines
+			p.NotCoveredLines = fr.NotCoveredLines
+			p.Covered = lineRanges(fr.Covered)
+			p.NotCovered = lineRanges(fr.NotCovered)
+		}
+		r.Policies = append(r.Policies, p)
+		r.CoveredLines += p.CoveredLines
+		r.NotCoveredLines += p.NotCoveredLines
+	}
+	if total := r.CoveredLines + r.NotCoveredLines; total > 0 {
+		r.Coverage = 100.0 * float64(r.CoveredLines) / float64(total)
+	}
+	return r
+}
+
+// lineRanges returns the lines of ranges, with adjacent and overlapping
+// lines collapsed into one range.
+func lineRanges(ranges []cover.Range) []LineRange {
+	lrs := make([]LineRange, 0, len(ranges))
+	for _, r := range ranges {
+		lrs = append(lrs, LineRange{Start: r.Start.Row, End: r.End.Row})
+	}
+	return collapseLines(lineNumbers(lrs))
+}
+
+// collapseLines returns sorted, unique line numbers as ranges of adjacent
+// lines.
+func collapseLines(rows []int) []LineRange {
+	var lrs []LineRange
+	for _, row := range rows {
+		if len(lrs) > 0 && lrs[len(lrs)-1].End == row-1 {
+			lrs[len(lrs)-1].End = row
+			continue
+		}
+		lrs = append(lrs, LineRange{Start: row, End: row})
+	}
+	return lrs
+}
+
+// lineNumbers returns the sorted, unique line numbers of ranges.
+func lineNumbers(ranges []LineRange) []int {
+	var rows []int
+	for _, r := range ranges {
+		for row := r.Start; row <= r.End; row++ {
+			rows = append(rows, row)
+		}
+	}
+	slices.Sort(rows)
+	return slices.Compact(rows)
+}
+
+// Below returns the policies whose coverage is below minCoverage percent.
+func (r CoverageReport) Below(minCoverage float64) []PolicyCoverage {
+	var below []PolicyCoverage
+	for _, p := range r.Policies {
+		if p.Coverage < minCoverage {
+			below = append(below, p)
+		}
+	}
+	return below
+}
+
+// String returns the coverage of each policy, the lines of it that were not
+// evaluated, and the total coverage.
+func (r CoverageReport) String() string {
+	var sb strings.Builder
+	sb.WriteString("OPA policy coverage:\n")
+	for _, p := range r.Policies {
+		fmt.Fprintf(&sb, "  %s: %.1f%%", p.File, p.Coverage)
+		if len(p.NotCovered) > 0 {
+			lines := make([]string, 0, len(p.NotCovered))
+			for _, lr := range p.NotCovered {
+				lines = append(lines, lr.String())
+			}
+			fmt.Fprintf(&sb, " (lines not covered: %s)", strings.Join(lines, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "Total: %.1f%%\n", r.Coverage)
+	return sb.String()
+}
+
+// WriteFile writes the coverage report to fileName in format.
+func (r CoverageReport) WriteFile(fileName, format string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("error creating coverage file %s: %w", fileName, err)
+	}
+	defer f.Close()
+	if err := r.Write(f, format); err != nil {
+		return fmt.Errorf("error writing coverage file %s: %w", fileName, err)
+	}
+	return f.Close()
+}
+
+// Write writes the coverage report to w in format.
+func (r CoverageReport) Write(w io.Writer, format string) error {
+	switch format {
+	case CoverageFormatLCOV:
+		return r.writeLCOV(w)
+	case CoverageFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return fmt.Errorf("coverage format %q is not one of %s", format, strings.Join(CoverageFormats, ", "))
+}
+
+// writeLCOV writes a record per policy in the lcov tracefile format, with a
+// hit for each line that was evaluated. A line with both evaluated and
+// unevaluated expressions counts as hit.
+func (r CoverageReport) writeLCOV(w io.Writer) error {
+	for _, p := range r.Policies {
+		covered := lineNumbers(p.Covered)
+		rows := slices.Concat(covered, lineNumbers(p.NotCovered))
+		slices.Sort(rows)
+		rows = slices.Compact(rows)
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "TN:\nSF:%s\n", p.File)
+		for _, row := range rows {
+			hits := 0
+			if _, ok := slices.BinarySearch(covered, row); ok {
+				hits = 1
+			}
+			fmt.Fprintf(&sb, "DA:%d,%d\n", row, hits)
+		}
+		fmt.Fprintf(&sb, "LF:%d\nLH:%d\nend_of_record\n", len(rows), len(covered))
+		if _, err := io.WriteString(w, sb.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
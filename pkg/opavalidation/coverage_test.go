@@ -0,0 +1,65 @@
+package opavalidation
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	fwrego "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/rego"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverage(t *testing.T) {
+	StartCoverage()
+	defer func() { coverage = nil }()
+	// Without a namespace, the policy stops evaluating at line 6.
+	_, err := Run("v0", "test/multipleRules.rego", "testdata/pod1.yaml", ExpectActionItemOptions{}, fwrego.InsightsInfo{}, "", "", nil)
+	assert.NoError(t, err)
+	r := Coverage()
+	if assert.Len(t, r.Policies, 1) {
+		p := r.Policies[0]
+		assert.Equal(t, "test/multipleRules.rego", p.File)
+		assert.Equal(t, []LineRange{{Start: 3, End: 3}, {Start: 6, End: 14}}, p.NotCovered)
+		assert.InDelta(t, 28.6, p.Coverage, 0.1)
+		assert.Equal(t, p.Coverage, r.Coverage)
+		assert.Len(t, r.Below(50), 1)
+		assert.Empty(t, r.Below(20))
+	}
+	assert.Contains(t, r.String(), "test/multipleRules.rego: 28.6% (lines not covered: 3, 6-14)")
+
+	// Coverage is combined with the evaluation using a namespace.
+	_, err = Run("v0", "test/multipleRules.rego", "testdata/pod1.yaml", ExpectActionItemOptions{Default: true, SuccessFileExtension: ".success.yaml", FailureFileExtension: ".failure.yaml"}, fwrego.InsightsInfo{}, "default", "", nil)
+	assert.NoError(t, err)
+	r = Coverage()
+	if assert.Len(t, r.Policies, 1) {
+		assert.Empty(t, r.Policies[0].NotCovered)
+		assert.Equal(t, 100.0, r.Coverage)
+	}
+}
+
+func TestWriteCoverage(t *testing.T) {
+	r := CoverageReport{
+		Policies: []PolicyCoverage{{
+			File:            "policy.rego",
+			Coverage:        50,
+			CoveredLines:    2,
+			NotCoveredLines: 2,
+			Covered:         []LineRange{{Start: 2, End: 3}},
+			NotCovered:      []LineRange{{Start: 3, End: 4}},
+		}},
+		Coverage:        50,
+		CoveredLines:    2,
+		NotCoveredLines: 2,
+	}
+	var b bytes.Buffer
+	assert.NoError(t, r.Write(&b, CoverageFormatLCOV))
+	assert.Equal(t, "TN:\nSF:policy.rego\nDA:2,1\nDA:3,1\nDA:4,0\nLF:3\nLH:2\nend_of_record\n", b.String())
+
+	b.Reset()
+	assert.NoError(t, r.Write(&b, CoverageFormatJSON))
+	var got CoverageReport
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Equal(t, r, got)
+
+	assert.Error(t, r.Write(&b, "xml"))
+}
@@ -0,0 +1,214 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opavalidation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// ExpectedFileExtension is the extension of a file listing the action items
+// an OPA policy is expected to output for a Kubernetes manifest, named of
+// the form {base rego filename}.{case}.expected.yaml.
+const ExpectedFileExtension = ".expected.yaml"
+
+// expectedActionItem is an action item an OPA policy is expected to output.
+// Fields which are not set are not compared.
+type expectedActionItem struct {
+	Title    string   `yaml:"title,omitempty"`
+	Severity *float64 `yaml:"severity,omitempty"`
+	Category string   `yaml:"category,omitempty"`
+	// Description is a regular expression which must match the whole
+	// description.
+	Description string `yaml:"description,omitempty"`
+	Remediation string `yaml:"remediation,omitempty"`
+}
+
+// readExpectedActionItems reads the list of expected action items in
+// fileName.
+func readExpectedActionItems(fileName string) ([]expectedActionItem, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading expected action items %s: %v", fileName, err)
+	}
+	var expected []expectedActionItem
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&expected)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing expected action items %s: %v", fileName, err)
+	}
+	for n, e := range expected {
+		_, err := regexp.Compile(descriptionPattern(e.Description))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the description of expected action item %d in %s: %v", n+1, fileName, err)
+		}
+	}
+	return expected, nil
+}
+
+// descriptionPattern anchors the regular expression of an expected
+// description to match the whole description.
+func descriptionPattern(description string) string {
+	return "^(?:" + description + ")$"
+}
+
+// matches returns whether AI matches the fields of e which are set.
+func (e expectedActionItem) matches(AI actionItem) bool {
+	return cmp.Equal(e, e.view(AI))
+}
+
+// view returns the fields of AI which are set in e, for comparison with e.
+// The description is replaced with the regular expression of e if it
+// matches.
+func (e expectedActionItem) view(AI actionItem) expectedActionItem {
+	var v expectedActionItem
+	if e.Title != "" {
+		v.Title = AI.Title
+	}
+	if e.Severity != nil {
+		severity := AI.Severity
+		v.Severity = &severity
+	}
+	if e.Category != "" {
+		v.Category = AI.Category
+	}
+	if e.Description != "" {
+		v.Description = AI.Description
+		if regexp.MustCompile(descriptionPattern(e.Description)).MatchString(AI.Description) {
+			v.Description = e.Description
+		}
+	}
+	if e.Remediation != "" {
+		v.Remediation = AI.Remediation
+	}
+	return v
+}
+
+// diffExpectedActionItems returns a unified diff of the expected action
+// items, read from expectedFileName, and the actual action items as YAML, or
+// an empty string if they match in any order.
+// Expected action items are paired with the actual action items they match,
+// pairing as many as possible, and those left with the first unpaired actual
+// action item, to show how they differ.
+func diffExpectedActionItems(expectedFileName string, expected []expectedActionItem, actual actionItems) (string, error) {
+	pairs, paired := pairExpectedActionItems(expected, actual)
+	for n := range expected {
+		if pairs[n] != -1 {
+			continue
+		}
+		for m := range actual {
+			if !paired[m] {
+				pairs[n], paired[m] = m, true
+				break
+			}
+		}
+	}
+	views := make([]expectedActionItem, 0, len(actual))
+	for n, e := range expected {
+		if pairs[n] != -1 {
+			views = append(views, e.view(actual[pairs[n]]))
+		}
+	}
+	for m, AI := range actual {
+		if !paired[m] {
+			severity := AI.Severity
+			views = append(views, expectedActionItem{Title: AI.Title, Severity: &severity, Category: AI.Category, Description: AI.Description, Remediation: AI.Remediation})
+		}
+	}
+	if cmp.Equal(expected, views, cmpopts.EquateEmpty()) {
+		return "", nil
+	}
+	expectedYAML, err := yaml.Marshal(expected)
+	if err != nil {
+		return "", err
+	}
+	actualYAML, err := yaml.Marshal(views)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(string(expectedYAML)),
+		B:        splitLines(string(actualYAML)),
+		FromFile: expectedFileName,
+		ToFile:   "actual",
+		Context:  3,
+	})
+}
+
+// pairExpectedActionItems pairs as many expected action items as possible
+// with an actual action item they match, as an expected action item may
+// match several actual action items. It returns the index of the actual
+// action item paired with each expected action item, or -1, and whether each
+// actual action item is paired.
+func pairExpectedActionItems(expected []expectedActionItem, actual actionItems) (pairs []int, paired []bool) {
+	matches := make([][]bool, len(expected))
+	for n, e := range expected {
+		matches[n] = make([]bool, len(actual))
+		for m, AI := range actual {
+			matches[n][m] = e.matches(AI)
+		}
+	}
+	pairs = make([]int, len(expected))
+	for n := range pairs {
+		pairs[n] = -1
+	}
+	pairedWith := make([]int, len(actual))
+	for m := range pairedWith {
+		pairedWith[m] = -1
+	}
+	// pair pairs expected action item n with an actual action item it
+	// matches, moving the expected action items already paired with one to
+	// another they match if needed, as in Kuhn's matching algorithm.
+	var pair func(n int, visited []bool) bool
+	pair = func(n int, visited []bool) bool {
+		for m := range actual {
+			if visited[m] || !matches[n][m] {
+				continue
+			}
+			visited[m] = true
+			if pairedWith[m] == -1 || pair(pairedWith[m], visited) {
+				pairs[n], pairedWith[m] = m, n
+				return true
+			}
+		}
+		return false
+	}
+	paired = make([]bool, len(actual))
+	for n := range expected {
+		pair(n, make([]bool, len(actual)))
+	}
+	for m := range actual {
+		paired[m] = pairedWith[m] != -1
+	}
+	return pairs, paired
+}
+
+// splitLines splits s, which ends with a newline, into lines including their
+// newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	return lines[:len(lines)-1]
+}
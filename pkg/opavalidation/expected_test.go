@@ -0,0 +1,99 @@
+package opavalidation
+
+import (
+	"testing"
+
+	fwrego "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/rego"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWithExpectedActionItems(t *testing.T) {
+	opts := ExpectActionItemOptions{SuccessFileExtension: ".success.yaml", FailureFileExtension: ".failure.yaml"}
+	ais, err := Run("v0", "testdata/expected/labels.rego", "testdata/expected/labels.nolabels.failure.yaml", opts, fwrego.InsightsInfo{}, "", "", nil)
+	assert.NoError(t, err)
+	assert.Len(t, ais, 2)
+
+	_, output, err := run("v0", "testdata/expected/labels.rego", "testdata/expected/labels.regression.failure.yaml", opts, fwrego.InsightsInfo{}, "", "", nil)
+	if assert.Error(t, err) {
+		assert.Equal(t, "action items do not match those expected in testdata/expected/labels.regression.expected.yaml", err.Error())
+		assert.Contains(t, output, `--- testdata/expected/labels.regression.expected.yaml
++++ actual
+@@ -1,3 +1,8 @@
+ - title: Label app is missing
+-  severity: 0.5
++  severity: 0.2
+   description: The Pod \w+ has no app label
++- title: Label team is missing
++  severity: 0.2
++  category: Reliability
++  description: The Pod nolabels has no team label
++  remediation: Add the label
+`)
+	}
+}
+
+func TestDiffExpectedActionItems(t *testing.T) {
+	severity := 0.2
+	actual := actionItems{
+		{Title: "Label app is missing", Description: "The Pod p has no app label", Severity: 0.2, Category: "Reliability"},
+	}
+	for _, tc := range []struct {
+		name      string
+		expected  []expectedActionItem
+		wantMatch bool
+	}{
+		{name: "all fields", expected: []expectedActionItem{{Title: "Label app is missing", Severity: &severity, Description: "The Pod .* has no app label"}}, wantMatch: true},
+		{name: "some fields", expected: []expectedActionItem{{Category: "Reliability"}}, wantMatch: true},
+		{name: "partial description", expected: []expectedActionItem{{Description: "has no app label"}}},
+		{name: "missing action item", expected: []expectedActionItem{{Title: "Label app is missing"}, {Title: "Label team is missing"}}},
+		{name: "unexpected action item"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diff, err := diffExpectedActionItems("expected.yaml", tc.expected, actual)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantMatch, diff == "", diff)
+		})
+	}
+}
+
+func TestDiffExpectedActionItemsOverlapping(t *testing.T) {
+	actual := actionItems{
+		{Title: "Label app is missing", Description: "The Pod p has no app label"},
+		{Title: "Label team is missing", Description: "The Pod p has no team label"},
+		{Title: "Label app is missing", Description: "The Pod q has no app label"},
+	}
+	// the first expected action item matches every actual action item, so
+	// pairing it with the first it matches leaves none for the second
+	expected := []expectedActionItem{
+		{Description: "The Pod . has no .* label"},
+		{Description: "The Pod p has no app label"},
+		{Title: "Label app is missing"},
+	}
+	diff, err := diffExpectedActionItems("expected.yaml", expected, actual)
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	pairs, paired := pairExpectedActionItems(expected, actual)
+	assert.Equal(t, []int{1, 0, 2}, pairs)
+	assert.Equal(t, []bool{true, true, true}, paired)
+
+	expected = append(expected, expectedActionItem{Title: "Label team is missing"})
+	diff, err = diffExpectedActionItems("expected.yaml", expected, actual)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "-- title: Label team is missing", "more action items are expected than output")
+}
+
+func TestExpectedFileNameFor(t *testing.T) {
+	opts := ExpectActionItemOptions{SuccessFileExtension: ".success.yaml", FailureFileExtension: ".failure.yaml"}
+	assert.Equal(t, "dir/policy.case.expected.yaml", opts.expectedFileNameFor("dir/policy.case.failure.yaml"))
+	assert.Equal(t, "dir/policy.expected.yaml", opts.expectedFileNameFor("dir/policy.FAILURE.yaml"))
+	assert.Equal(t, "dir/policy.expected.yaml", opts.expectedFileNameFor("dir/policy.yaml"))
+}
+
+func TestReadExpectedActionItems(t *testing.T) {
+	expected, err := readExpectedActionItems("testdata/expected/labels.nolabels.expected.yaml")
+	assert.NoError(t, err)
+	assert.Len(t, expected, 2)
+	_, err = readExpectedActionItems("testdata/expected/labels.rego")
+	assert.Error(t, err)
+}
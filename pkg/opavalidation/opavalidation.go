@@ -12,12 +12,13 @@ import (
 	"strings"
 
 	fwrego "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/rego"
-	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/topdown"
 	"github.com/open-policy-agent/opa/v1/types"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
+
+	"github.com/fairwindsops/insights-cli/pkg/report"
 )
 
 const (
@@ -26,26 +27,45 @@ const (
 
 // Run is a ValidateRego() wrapper that validates and prints resulting actionItems. This is
 // meant to be called from a cobra.Command{}.
-func Run(regoVersion, regoFileName, objectFileName string, expectAIOptions ExpectActionItemOptions, insightsInfo fwrego.InsightsInfo, objectNamespaceOverride, libsDir string) (actionItems, error) {
+// The result is recorded as a test case of the policy for reports.
+// The kubernetes rego function returns objects from clusterFixtures, if it
+// is not nil.
+// If the Kubernetes manifest expects action items and a file of expected
+// action items exists for it, the action items must match those instead.
+func Run(regoVersion, regoFileName, objectFileName string, expectAIOptions ExpectActionItemOptions, insightsInfo fwrego.InsightsInfo, objectNamespaceOverride, libsDir string, clusterFixtures ClusterFixtures) (actionItems, error) {
+	actionItems, output, err := run(regoVersion, regoFileName, objectFileName, expectAIOptions, insightsInfo, objectNamespaceOverride, libsDir, clusterFixtures)
+	testCase := report.TestCase{Suite: regoFileName, Name: objectFileName, File: regoFileName, Output: output}
+	if err != nil {
+		// The action items include why each is invalid.
+		testCase.Failure = strings.TrimSpace(err.Error() + "\n\n" + output)
+		testCase.Output = ""
+	}
+	report.Record(testCase)
+	return actionItems, err
+}
+
+// run validates and prints resulting actionItems, returning them and their
+// string representation.
+func run(regoVersion, regoFileName, objectFileName string, expectAIOptions ExpectActionItemOptions, insightsInfo fwrego.InsightsInfo, objectNamespaceOverride, libsDir string, clusterFixtures ClusterFixtures) (actionItems, string, error) {
 	b, err := os.ReadFile(regoFileName)
 	if err != nil {
-		return nil, fmt.Errorf("error reading OPA policy %s: %v", regoFileName, err)
+		return nil, "", fmt.Errorf("error reading OPA policy %s: %v", regoFileName, err)
 	}
 	regoContent := string(b)
 	b, err = os.ReadFile(objectFileName)
 	if err != nil {
-		return nil, fmt.Errorf("error reading Kubernetes manifest %s: %v", objectFileName, err)
+		return nil, "", fmt.Errorf("error reading Kubernetes manifest %s: %v", objectFileName, err)
 	}
 	libs := map[string]string{}
 	if libsDir != "" {
 		files, err := FindFilesWithExtension(libsDir, ".rego")
 		if err != nil {
-			return nil, fmt.Errorf("unable to list .rego files in %s: %v", libsDir, err)
+			return nil, "", fmt.Errorf("unable to list .rego files in %s: %v", libsDir, err)
 		}
 		for _, lib := range files {
 			libContent, err := os.ReadFile(lib)
 			if err != nil {
-				return nil, fmt.Errorf("error reading OPA library %s: %v", lib, err)
+				return nil, "", fmt.Errorf("error reading OPA library %s: %v", lib, err)
 			}
 			if !IsOPACustomLibrary(string(libContent)) {
 				logrus.Warnf("Skipping non-OPA library %s", lib)
@@ -58,25 +78,42 @@ func Run(regoVersion, regoFileName, objectFileName string, expectAIOptions Expec
 
 	baseRegoFileName := filepath.Base(regoFileName)
 	eventType := strings.TrimSuffix(baseRegoFileName, filepath.Ext(baseRegoFileName))
-	actionItems, err := ValidateRego(context.TODO(), regoContent, regoVersion, b, insightsInfo, eventType, objectNamespaceOverride, libs)
+	ctx := withCoverageTracer(context.TODO(), regoFileName, regoContent, regoVersion)
+	actionItems, err := ValidateRego(ctx, regoContent, regoVersion, b, insightsInfo, eventType, objectNamespaceOverride, libs, clusterFixtures)
 	if err != nil {
-		return actionItems, err
+		return actionItems, "", err
 	}
 	actionItemsAsString, err := actionItems.StringWithValidation()
 	// If actionItems have errors, output the actionItems first to display more
 	// specific inline errors.
 	fmt.Println(actionItemsAsString)
 	if err != nil {
-		return actionItems, err
+		return actionItems, actionItemsAsString, err
 	}
 	expectAI := expectAIOptions.ForFileName(objectFileName)
+	expectedFileName := expectAIOptions.expectedFileNameFor(objectFileName)
+	if _, err := os.Stat(expectedFileName); expectAI && err == nil {
+		expected, err := readExpectedActionItems(expectedFileName)
+		if err != nil {
+			return actionItems, actionItemsAsString, err
+		}
+		diff, err := diffExpectedActionItems(expectedFileName, expected, actionItems)
+		if err != nil {
+			return actionItems, actionItemsAsString, fmt.Errorf("error comparing action items with those expected in %s: %v", expectedFileName, err)
+		}
+		if diff != "" {
+			fmt.Print(diff)
+			return actionItems, actionItemsAsString + "\n" + diff, fmt.Errorf("action items do not match those expected in %s", expectedFileName)
+		}
+		return actionItems, actionItemsAsString, nil
+	}
 	if expectAI && len(actionItems) != 1 {
-		return actionItems, fmt.Errorf("%d action items were returned, but 1 is expected", len(actionItems))
+		return actionItems, actionItemsAsString, fmt.Errorf("%d action items were returned, but 1 is expected", len(actionItems))
 	}
 	if !expectAI && len(actionItems) > 0 {
-		return actionItems, fmt.Errorf("%d action items were returned but none are expected", len(actionItems))
+		return actionItems, actionItemsAsString, fmt.Errorf("%d action items were returned but none are expected", len(actionItems))
 	}
-	return actionItems, nil
+	return actionItems, actionItemsAsString, nil
 }
 
 // RunBatch is a Run() wrapper that processes multiple OPA policies. It does
@@ -86,7 +123,15 @@ func Run(regoVersion, regoFileName, objectFileName string, expectAIOptions Expec
 // Each OPA policy is validated with a Kubernetes manifest file named of the
 // form {base rego filename} and the extensions .yaml, .success.yaml, and
 // .failure.yaml (the last two of which are configurable).
-func RunBatch(regoVersion, batchDir string, expectAIOptions ExpectActionItemOptions, insightsInfo fwrego.InsightsInfo, objectNamespaceOverride, libsDir string) (successfulPolicies, failedPolicies []string, err error) {
+// The action items output for a manifest which expects them are compared with
+// those listed in a file named of the form {base rego filename}.{case}.expected.yaml,
+// if it exists, instead of expecting one action item.
+// When StartCoverage was called, the coverage of each policy is combined
+// across all of its manifests.
+// The kubernetes rego function returns objects from the manifests in
+// clusterFixturesDir, if it is not empty, and in a directory named of the
+// form {base rego filename}.cluster-fixtures, if it exists.
+func RunBatch(regoVersion, batchDir string, expectAIOptions ExpectActionItemOptions, insightsInfo fwrego.InsightsInfo, objectNamespaceOverride, libsDir, clusterFixturesDir string) (successfulPolicies, failedPolicies []string, err error) {
 	regoFiles, err := FindFilesWithExtension(batchDir, ".rego")
 	if err != nil {
 		return successfulPolicies, failedPolicies, fmt.Errorf("unable to list .rego files: %v", err)
@@ -98,12 +143,17 @@ func RunBatch(regoVersion, batchDir string, expectAIOptions ExpectActionItemOpti
 		}
 		if !ok {
 			logrus.Errorf("No Kubernetes manifest files found to use as input for validation of OPA policy %s", regoFileName)
+			report.Record(report.TestCase{Suite: regoFileName, File: regoFileName, Failure: "no Kubernetes manifest files found to use as input"})
 			failedPolicies = append(failedPolicies, regoFileName)
 			continue
 		}
+		clusterFixtures, err := clusterFixturesForPolicy(regoFileName, clusterFixturesDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading cluster fixtures for policy %s: %w", regoFileName, err)
+		}
 		for _, objectFileName := range objectFileNames {
 			logrus.Infof("Validating OPA policy %s with input %s (expectActionItem=%v)", regoFileName, objectFileName, expectAIOptions.ForFileName(objectFileName))
-			_, err := Run(regoVersion, regoFileName, objectFileName, expectAIOptions, insightsInfo, objectNamespaceOverride, libsDir)
+			_, err := Run(regoVersion, regoFileName, objectFileName, expectAIOptions, insightsInfo, objectNamespaceOverride, libsDir, clusterFixtures)
 			if err != nil {
 				logrus.Errorf("Failed validation of OPA policy %s using input %s: %v\n", regoFileName, objectFileName, err)
 				if !lo.Contains(failedPolicies, regoFileName) {
@@ -124,7 +174,9 @@ func RunBatch(regoVersion, batchDir string, expectAIOptions ExpectActionItemOpti
 
 // ValidateRego validates rego by executing rego with an input object.
 // Validation includes signatures for Insights-provided rego functions.
-func ValidateRego(ctx context.Context, regoAsString string, regoVersion string, objectAsBytes []byte, insightsInfo fwrego.InsightsInfo, eventType string, objectNamespaceOverride string, libs map[string]string) (actionItems, error) {
+// The kubernetes rego function returns objects from clusterFixtures, if it
+// is not nil.
+func ValidateRego(ctx context.Context, regoAsString string, regoVersion string, objectAsBytes []byte, insightsInfo fwrego.InsightsInfo, eventType string, objectNamespaceOverride string, libs map[string]string, clusterFixtures ClusterFixtures) (actionItems, error) {
 	if !strings.Contains(regoAsString, "package fairwinds") {
 		return nil, errors.New("policy must be within a fairwinds package. The policy must contain the statement: package fairwinds")
 	}
@@ -136,7 +188,7 @@ func ValidateRego(ctx context.Context, regoAsString string, regoVersion string,
 	if err != nil {
 		return nil, fmt.Errorf("while overriding object namespace with %q: %v", objectNamespaceOverride, err)
 	}
-	regoResult, err := runRegoForObject(ctx, regoAsString, regoVersion, objectAsMap, insightsInfo, libs)
+	regoResult, err := runRegoForObject(ctx, regoAsString, regoVersion, objectAsMap, insightsInfo, libs, clusterFixtures)
 	if err != nil {
 		return nil, err
 	}
@@ -153,23 +205,18 @@ func ValidateRego(ctx context.Context, regoAsString string, regoVersion string,
 }
 
 // runRegoForObject executes rego with a Kubernetes object as input.
-func runRegoForObject(ctx context.Context, regoAsString string, regoVersion string, object map[string]any, insightsInfo fwrego.InsightsInfo, libs map[string]string) (rego.ResultSet, error) {
+// The rego kubernetes function returns objects from clusterFixtures.
+// Coverage of the rego is recorded when ctx carries a coverage tracer.
+func runRegoForObject(ctx context.Context, regoAsString string, regoVersion string, object map[string]any, insightsInfo fwrego.InsightsInfo, libs map[string]string, clusterFixtures ClusterFixtures) (rego.ResultSet, error) {
 	opts := []func(r *rego.Rego){rego.EnablePrintStatements(true), rego.PrintHook(topdown.NewPrintHook(os.Stdout)),
 		rego.Query("results = data"),
-		rego.Module("fairwinds", regoAsString),
+		rego.Module(policyModuleName, regoAsString),
 		rego.Function2(
 			&rego.Function{
 				Name: "kubernetes",
 				Decl: types.NewFunction(types.Args(types.S, types.S), types.A),
 			},
-			func(_ rego.BuiltinContext, _ *ast.Term, _ *ast.Term) (*ast.Term, error) {
-				logrus.Warnln("NOTE: The rego kubernetes function currently does not return data when validating OPA policies.")
-				returnData, err := ast.InterfaceToValue([]string{"the rego kubernetes function currently does not return data when validating OPA policies"})
-				if err != nil {
-					return nil, err
-				}
-				return ast.NewTerm(returnData), nil
-			},
+			kubernetesFunction(clusterFixtures),
 		),
 		rego.Function1(
 			&rego.Function{
@@ -179,11 +226,7 @@ func runRegoForObject(ctx context.Context, regoAsString string, regoVersion stri
 			fwrego.GetInsightsInfoFunction(&insightsInfo),
 		),
 	}
-	if regoVersion == "v1" {
-		opts = append(opts, rego.SetRegoVersion(ast.RegoV1))
-	} else {
-		opts = append(opts, rego.SetRegoVersion(ast.RegoV0))
-	}
+	opts = append(opts, rego.SetRegoVersion(astRegoVersion(regoVersion)))
 	var libNames []string
 	for libName := range libs {
 		libNames = append(libNames, libName)
@@ -196,8 +239,11 @@ func runRegoForObject(ctx context.Context, regoAsString string, regoVersion stri
 	if err != nil {
 		return nil, fmt.Errorf("error preparing rego for evaluation: %v", err)
 	}
-	preparedInput := rego.EvalInput(object)
-	rs, err := query.Eval(ctx, preparedInput)
+	evalOpts := []rego.EvalOption{rego.EvalInput(object)}
+	if tracer, ok := coverageTracerFrom(ctx); ok {
+		evalOpts = append(evalOpts, rego.EvalQueryTracer(tracer))
+	}
+	rs, err := query.Eval(ctx, evalOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("error evaluating rego: %v", err)
 	}
@@ -0,0 +1,14 @@
+package fairwinds
+
+labelsrequired[actionItem] {
+    requiredLabels := ["app", "team"]
+    label := requiredLabels[_]
+    not input.metadata.labels[label]
+    actionItem := {
+        "title": sprintf("Label %v is missing", [label]),
+        "description": sprintf("The %v %v has no %v label", [input.kind, input.metadata.name, label]),
+        "severity": 0.2,
+        "remediation": "Add the label",
+        "category": "Reliability"
+    }
+}
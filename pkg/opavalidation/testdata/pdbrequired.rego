@@ -0,0 +1,15 @@
+package fairwinds
+
+pdbrequired[actionItem] {
+    input.kind == "Deployment"
+    pdbs := kubernetes("PodDisruptionBudget", input.metadata.namespace)
+    matching := [pdb | pdb := pdbs[_]; pdb.spec.selector.matchLabels.app == input.metadata.labels.app]
+    count(matching) == 0
+    actionItem := {
+        "title": "PodDisruptionBudget is missing",
+        "description": sprintf("No PodDisruptionBudget selects the Deployment %v", [input.metadata.name]),
+        "severity": 0.3,
+        "remediation": "Add a PodDisruptionBudget",
+        "category": "Reliability"
+    }
+}
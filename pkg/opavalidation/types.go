@@ -191,6 +191,17 @@ func (o ExpectActionItemOptions) ForFileName(fileName string) bool {
 	return o.Default
 }
 
+// expectedFileNameFor returns the name of the file listing the action items
+// expected for the given Kubernetes manifest file name. The
+// FailureFileExtension, or else the file extension, of the manifest is
+// replaced with ExpectedFileExtension.
+func (o ExpectActionItemOptions) expectedFileNameFor(fileName string) string {
+	if o.FailureFileExtension != "" && strings.HasSuffix(strings.ToLower(fileName), strings.ToLower(o.FailureFileExtension)) {
+		return fileName[:len(fileName)-len(o.FailureFileExtension)] + ExpectedFileExtension
+	}
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ExpectedFileExtension
+}
+
 // getObjectFileNamesForPolicy returns a list of existing file names matching
 // the pattern {base rego file name}.yaml|{base rego file name}.<anything>.success.yaml|{base rego file name}.<anything>.failure.yaml (the
 // latter two being configurable via the expectActionItemOptions struct).
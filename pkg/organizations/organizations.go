@@ -1,7 +1,9 @@
 package organizations
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/fairwindsops/insights-cli/pkg/utils"
 	"github.com/imroc/req/v3"
@@ -10,6 +12,39 @@ import (
 
 const organizationURLFormat = "/v0/organizations/%s"
 
+// ErrUnauthorized is returned when Insights rejects the token for the
+// organization.
+var ErrUnauthorized = errors.New("token is not authorized for the organization")
+
+// Organization is an organization in Insights.
+type Organization struct {
+	Name           string `json:"Name"`
+	PolicyStrategy string `json:"PolicyStrategy"`
+}
+
+// GetOrganization returns the organization org, or ErrUnauthorized if the
+// token of client cannot access it.
+func GetOrganization(client *req.Client, org string) (*Organization, error) {
+	url := fmt.Sprintf(organizationURLFormat, org)
+	logrus.Debugf("getOrganization: organization URL: %s", url)
+	resp, err := client.R().SetHeaders(utils.GetHeaders("")).Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch organization from insights: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: response code %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.IsErrorState() {
+		return nil, fmt.Errorf("invalid response code - expected 200, got %d: %s", resp.StatusCode, string(resp.Bytes()))
+	}
+	var organization Organization
+	err = resp.Unmarshal(&organization)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert response to json for organization: %w", err)
+	}
+	return &organization, nil
+}
+
 func ManageOrganizationPolicyMappings(client *req.Client, org string, enable bool) error {
 	mode := "scan-all"
 	if enable {
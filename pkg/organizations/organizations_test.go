@@ -0,0 +1,37 @@
+package organizations
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrganization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		switch r.URL.Path {
+		case "/v0/organizations/acme":
+			_, _ = w.Write([]byte(`{"Name": "acme", "PolicyStrategy": "app-groups"}`))
+		case "/v0/organizations/other":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client := req.C().SetBaseURL(server.URL)
+
+	org, err := GetOrganization(client, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, &Organization{Name: "acme", PolicyStrategy: "app-groups"}, org)
+
+	_, err = GetOrganization(client, "other")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = GetOrganization(client, "broken")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,331 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package overlay applies per-environment changes to the resource files of a
+// push directory, similar to kustomize bases and overlays.
+//
+// The overlay named prod is the directory overlays/prod within the push
+// directory, which mirrors the layout of the push directory. A file in the
+// overlay with the same path as a file in the push directory is a merge
+// patch, as described by RFC 7386, of that file. A file named like a file in
+// the push directory, with .patch added before its extension, is a list of
+// JSON patch operations, as described by RFC 6902, written as YAML. Other
+// files in the overlay are added to those of the push directory.
+package overlay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/fairwindsops/insights-cli/pkg/variables"
+)
+
+// DirName is the directory, within the push directory, holding overlays.
+const DirName = "overlays"
+
+// patchSuffix is added before the extension of the file a JSON patch
+// applies to.
+const patchSuffix = ".patch"
+
+// Overlay holds the location of an overlay and of the push directory it
+// applies to.
+type Overlay struct {
+	Base string
+	Dir  string
+}
+
+// New returns the overlay with the given name in the push directory base.
+func New(base, name string) (Overlay, error) {
+	dir := filepath.Join(base, DirName, name)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Overlay{}, fmt.Errorf("unable to read overlay %s: %w", name, err)
+	}
+	if !info.IsDir() {
+		return Overlay{}, fmt.Errorf("overlay %s is not a directory", dir)
+	}
+	return Overlay{Base: base, Dir: dir}, nil
+}
+
+var current Overlay
+
+// Set sets the overlay applied to resource files read by ReadFile and
+// ScanFolder.
+func Set(o Overlay) {
+	current = o
+}
+
+// Path returns the directory of the overlay set by Set that mirrors folder
+// in the push directory, or an empty string if no overlay is set or folder
+// is not in the push directory.
+func Path(folder string) string {
+	if current.Dir == "" || !isWithin(current.Base, folder) {
+		return ""
+	}
+	rel, err := filepath.Rel(current.Base, folder)
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(current.Dir, rel)
+}
+
+// ScanFolder scans folder with scan, which is one of the scan functions of
+// the directory package, and adds the files of the overlay set by Set that
+// are not patches of files in folder.
+func ScanFolder(folder string, scan func(string) (map[string][]string, error)) (map[string][]string, error) {
+	files, err := scan(folder)
+	if err != nil {
+		return nil, err
+	}
+	overlayFolder := Path(folder)
+	if overlayFolder == "" || !exists(overlayFolder) {
+		return files, nil
+	}
+	overlayFiles, err := scan(overlayFolder)
+	if err != nil {
+		return nil, err
+	}
+	for key, paths := range overlayFiles {
+		for _, path := range paths {
+			rel, err := filepath.Rel(overlayFolder, path)
+			if err != nil {
+				return nil, err
+			}
+			basePath := filepath.Join(folder, rel)
+			if patched, ok := patchedFile(basePath); ok {
+				if !exists(patched) {
+					return nil, fmt.Errorf("overlay patch %s has no matching file %s", path, patched)
+				}
+				continue
+			}
+			if exists(basePath) {
+				continue
+			}
+			files[key] = append(files[key], path)
+		}
+	}
+	return files, nil
+}
+
+// ReadFile returns the content of the file at path, with the overlay set by
+// Set applied.
+func ReadFile(path string) ([]byte, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	overlayPath := Path(path)
+	if overlayPath == "" || isWithin(current.Dir, path) {
+		return content, nil
+	}
+	if exists(overlayPath) {
+		patch, err := readFile(overlayPath)
+		if err != nil {
+			return nil, err
+		}
+		if !isYAML(path) {
+			return patch, nil
+		}
+		content, err = mergeFile(content, patch)
+		if err != nil {
+			return nil, fmt.Errorf("error merging overlay %s: %w", overlayPath, err)
+		}
+	}
+	if !isYAML(path) {
+		return content, nil
+	}
+	ext := filepath.Ext(overlayPath)
+	patchPath := strings.TrimSuffix(overlayPath, ext) + patchSuffix + ext
+	if exists(patchPath) {
+		patch, err := readFile(patchPath)
+		if err != nil {
+			return nil, err
+		}
+		content, err = patchFile(content, patch)
+		if err != nil {
+			return nil, fmt.Errorf("error applying overlay %s: %w", patchPath, err)
+		}
+	}
+	return content, nil
+}
+
+// readFile reads the file at path, expanding variables in YAML files.
+func readFile(path string) ([]byte, error) {
+	if isYAML(path) {
+		return variables.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+// mergeFile merges each document of patch into the document of content with
+// the same name, or into the only document of content. Documents of patch
+// that match no document of content are added to it.
+func mergeFile(content, patch []byte) ([]byte, error) {
+	docs, err := decodeAll(content)
+	if err != nil {
+		return nil, err
+	}
+	patchDocs, err := decodeAll(patch)
+	if err != nil {
+		return nil, err
+	}
+	for _, patchDoc := range patchDocs {
+		i := matchingDocument(docs, patchDoc)
+		if i < 0 {
+			docs = append(docs, patchDoc)
+			continue
+		}
+		docs[i], err = applyJSON(docs[i], patchDoc, jsonpatch.MergePatch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encodeAll(docs)
+}
+
+func matchingDocument(docs []any, patchDoc any) int {
+	name := documentName(patchDoc)
+	if name == "" && len(docs) == 1 {
+		return 0
+	}
+	for i, doc := range docs {
+		if name != "" && documentName(doc) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func documentName(doc any) string {
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return ""
+	}
+	name, _ := m["name"].(string)
+	return name
+}
+
+// patchFile applies the JSON patch operations of patch to the single
+// document of content.
+func patchFile(content, patch []byte) ([]byte, error) {
+	docs, err := decodeAll(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) != 1 {
+		return nil, fmt.Errorf("a JSON patch applies to a file with one document, found %d", len(docs))
+	}
+	var operations any
+	err = yaml.Unmarshal(patch, &operations)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := applyJSON(docs[0], operations, func(doc, patch []byte) ([]byte, error) {
+		p, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		return p.Apply(doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return encodeAll([]any{doc})
+}
+
+// applyJSON applies patch to doc, both as decoded from YAML, with apply,
+// which takes and returns JSON.
+func applyJSON(doc, patch any, apply func(doc, patch []byte) ([]byte, error)) (any, error) {
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	patchedJSON, err := apply(docJSON, patchJSON)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is decoded as YAML, to keep integers as such
+	var patched any
+	err = yaml.Unmarshal(patchedJSON, &patched)
+	return patched, err
+}
+
+func decodeAll(content []byte) ([]any, error) {
+	var docs []any
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func encodeAll(docs []any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// patchedFile returns the file patched by the JSON patch at path, and false
+// if path is not a JSON patch.
+func patchedFile(path string) (string, bool) {
+	ext := filepath.Ext(path)
+	if !isYAML(path) || !strings.HasSuffix(strings.TrimSuffix(path, ext), patchSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(path, ext), patchSuffix) + ext, true
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,121 @@
+package overlay
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fairwindsops/insights-cli/pkg/directory"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestOverlay(t *testing.T) {
+	base := t.TempDir()
+	writeFile(t, filepath.Join(base, "app-groups", "groups.yaml"), `name: web
+spec:
+  match:
+    - clusters: [staging]
+---
+name: api
+spec:
+  match:
+    - clusters: [staging]
+`)
+	writeFile(t, filepath.Join(base, "policy-mappings", "block.yaml"), `name: block
+spec:
+  block: false
+  appGroups: [web]
+`)
+	writeFile(t, filepath.Join(base, DirName, "prod", "app-groups", "groups.yaml"), `name: api
+spec:
+  match:
+    - clusters: [prod]
+`)
+	writeFile(t, filepath.Join(base, DirName, "prod", "app-groups", "extra.yaml"), "name: extra\n")
+	writeFile(t, filepath.Join(base, DirName, "prod", "policy-mappings", "block.patch.yaml"), `- op: replace
+  path: /spec/block
+  value: true
+- op: add
+  path: /spec/appGroups/-
+  value: api
+`)
+
+	_, err := New(base, "missing")
+	assert.Error(t, err)
+	o, err := New(base, "prod")
+	assert.NoError(t, err)
+	Set(o)
+	defer Set(Overlay{})
+
+	files, err := ScanFolder(filepath.Join(base, "app-groups"), directory.ScanFolder)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(base, "app-groups", "groups.yaml"),
+		filepath.Join(base, DirName, "prod", "app-groups", "extra.yaml"),
+	}, files["app-groups"])
+
+	content, err := ReadFile(filepath.Join(base, "app-groups", "groups.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, `name: web
+spec:
+  match:
+    - clusters:
+        - staging
+---
+name: api
+spec:
+  match:
+    - clusters:
+        - prod
+`, string(content))
+
+	content, err = ReadFile(filepath.Join(base, "policy-mappings", "block.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, `name: block
+spec:
+  appGroups:
+    - web
+    - api
+  block: true
+`, string(content))
+
+	writeFile(t, filepath.Join(base, DirName, "prod", "policy-mappings", "missing.patch.yaml"), "[]\n")
+	_, err = ScanFolder(filepath.Join(base, "policy-mappings"), directory.ScanFolder)
+	assert.Error(t, err)
+}
+
+func TestPatchFile(t *testing.T) {
+	content := []byte("a:\n- 1\n- 2\nb:\n  c~/: x\n")
+	patched, err := patchFile(content, []byte(`
+- {op: test, path: /b/c~0~1, value: x}
+- {op: add, path: /a/0, value: 0}
+- {op: remove, path: /a/2}
+- {op: move, from: /b/c~0~1, path: /d}
+- {op: copy, from: /d, path: /b/e}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "a:\n  - 0\n  - 1\nb:\n  e: x\nd: x\n", string(patched))
+
+	_, err = patchFile(patched, []byte("- {op: test, path: /d, value: y}\n"))
+	assert.Error(t, err)
+	_, err = patchFile(patched, []byte("- {op: replace, path: /missing, value: y}\n"))
+	assert.Error(t, err)
+	_, err = patchFile(patched, []byte("- {op: add, path: /a/5, value: y}\n"))
+	assert.Error(t, err)
+	_, err = patchFile([]byte("a: 1\n---\nb: 2\n"), []byte("- {op: remove, path: /a}\n"))
+	assert.Error(t, err)
+}
+
+func TestMergeFile(t *testing.T) {
+	content := []byte("name: a\nspec:\n  replicas: 1\n  image: x\n---\nname: b\n")
+	merged, err := mergeFile(content, []byte("name: a\nspec:\n  replicas: 3\n  image: null\n---\nname: c\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "name: a\nspec:\n  replicas: 3\n---\nname: b\n---\nname: c\n", string(merged))
+}
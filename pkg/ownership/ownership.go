@@ -0,0 +1,51 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ownership records which resources in Insights were pushed by a
+// push directory, so that deleting resources missing from a push directory
+// leaves resources pushed by other repositories alone. Insights does not
+// store owners, so the resources pushed with an owner are recorded locally,
+// in the state file of the push directory.
+package ownership
+
+var owner string
+var owned map[string]bool
+
+// SetOwner sets the owner resources are pushed with.
+func SetOwner(o string) {
+	owner = o
+}
+
+// Owner returns the owner set by SetOwner, or an empty string if no owner is
+// set.
+func Owner() string {
+	return owner
+}
+
+// SetOwned sets the names of the resources, of the type being pushed, that
+// were recorded as pushed with the owner set by SetOwner.
+func SetOwned(names []string) {
+	owned = map[string]bool{}
+	for _, name := range names {
+		owned[name] = true
+	}
+}
+
+// Owns returns true if the resource in Insights with the given name may be
+// deleted because it is missing from the push directory. Without an owner,
+// every resource may be deleted. With an owner, only resources recorded as
+// pushed with it by SetOwned may be deleted.
+func Owns(name string) bool {
+	return owner == "" || owned[name]
+}
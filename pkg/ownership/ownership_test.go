@@ -0,0 +1,25 @@
+package ownership
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwns(t *testing.T) {
+	defer SetOwner("")
+	defer SetOwned(nil)
+	SetOwned([]string{"pushed"})
+
+	SetOwner("")
+	assert.True(t, Owns("pushed"))
+	assert.True(t, Owns("other"), "every resource is deletable without an owner")
+
+	SetOwner("acme/policies")
+	assert.Equal(t, "acme/policies", Owner())
+	assert.True(t, Owns("pushed"))
+	assert.False(t, Owns("other"), "resources not recorded as pushed with the owner are left alone")
+
+	SetOwned(nil)
+	assert.False(t, Owns("pushed"))
+}
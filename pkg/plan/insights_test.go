@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	opaPlugin "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/opa"
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+)
+
+// fakeInsights serves the OPA checks, rules, policies configuration and
+// app-groups of one organization from memory, and records the requests it receives. OPA checks
+// have no instances.
+type fakeInsights struct {
+	t         *testing.T
+	url       string
+	mu        sync.Mutex
+	checks    []opaPlugin.OPACustomCheck
+	rules     []rules.Rule
+	settings  string
+	appGroups []appgroups.AppGroup
+	// regoVersions holds the rego version each OPA check was put with.
+	regoVersions map[string]string
+	requests     []string
+}
+
+func newFakeInsights(t *testing.T) (*fakeInsights, *req.Client) {
+	f := &fakeInsights{t: t, regoVersions: map[string]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	f.url = server.URL
+	return f, f.client()
+}
+
+// client returns a new client for the fake API.
+func (f *fakeInsights) client() *req.Client {
+	return req.C().SetBaseURL(f.url)
+}
+
+// Requests returns the method and path of each request received.
+func (f *fakeInsights) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.requests...)
+}
+
+// Writes returns the requests received other than GETs.
+func (f *fakeInsights) Writes() []string {
+	var writes []string
+	for _, r := range f.Requests() {
+		if !strings.HasPrefix(r, http.MethodGet+" ") {
+			writes = append(writes, r)
+		}
+	}
+	return writes
+}
+
+func (f *fakeInsights) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	path := strings.TrimPrefix(r.URL.Path, "/v0/organizations/acme-co/")
+	switch {
+	case path == "opa/customChecks" && r.Method == http.MethodGet:
+		f.respond(w, f.checks)
+	case strings.HasSuffix(path, "/instances") && r.Method == http.MethodGet:
+		f.respond(w, []opaPlugin.CheckSetting{})
+	case strings.HasPrefix(path, "opa/customChecks/") && r.Method == http.MethodPut:
+		var body opa.PutCheckRequest
+		f.decode(r, &body)
+		name := strings.TrimPrefix(path, "opa/customChecks/")
+		f.checks = lo.Reject(f.checks, func(c opaPlugin.OPACustomCheck, _ int) bool { return c.Name == name })
+		f.checks = append(f.checks, opaPlugin.OPACustomCheck{Name: name, Version: 2, Rego: body.Rego, Description: body.Description, Disabled: body.Disabled,
+			Title: body.Title, Severity: body.Severity, Remediation: body.Remediation, Category: body.Category})
+		f.regoVersions[name] = body.RegoVersion
+		f.respond(w, body)
+	case strings.HasPrefix(path, "opa/customChecks/") && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(path, "opa/customChecks/")
+		f.checks = lo.Reject(f.checks, func(c opaPlugin.OPACustomCheck, _ int) bool { return c.Name == name })
+		f.respond(w, name)
+	case path == "rules" && r.Method == http.MethodGet:
+		f.respond(w, f.rules)
+	case path == "rules/create" && r.Method == http.MethodPost:
+		var rule rules.Rule
+		f.decode(r, &rule)
+		rule.ID = len(f.rules) + 100
+		f.rules = append(f.rules, rule)
+		f.respond(w, rule)
+	case strings.HasPrefix(path, "rules/"):
+		id, err := strconv.Atoi(strings.TrimPrefix(path, "rules/"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var rule rules.Rule
+		if r.Method == http.MethodPost {
+			f.decode(r, &rule)
+		}
+		for i := range f.rules {
+			if f.rules[i].ID == id {
+				if r.Method == http.MethodDelete {
+					f.rules = append(f.rules[:i], f.rules[i+1:]...)
+				} else {
+					f.rules[i] = rule
+				}
+				f.respond(w, rule)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case path == "policies" && r.Method == http.MethodGet:
+		_, err := w.Write([]byte(f.settings))
+		if err != nil {
+			f.t.Errorf("unable to write response: %v", err)
+		}
+	case path == "policies" && r.Method == http.MethodPost:
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			f.t.Errorf("unable to read %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		f.settings = string(b)
+	case path == "app-groups" && r.Method == http.MethodGet:
+		f.respond(w, f.appGroups)
+	case path == "app-groups" && r.Method == http.MethodPost:
+		// app-groups are upserted by name
+		var appGroup appgroups.AppGroup
+		f.decode(r, &appGroup)
+		f.appGroups = lo.Reject(f.appGroups, func(a appgroups.AppGroup, _ int) bool { return a.Name == appGroup.Name })
+		f.appGroups = append(f.appGroups, appGroup)
+		f.respond(w, appGroup)
+	case strings.HasPrefix(path, "app-groups/") && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(path, "app-groups/")
+		for i := range f.appGroups {
+			if f.appGroups[i].Name == name {
+				f.respond(w, f.appGroups[i])
+				f.appGroups = append(f.appGroups[:i], f.appGroups[i+1:]...)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeInsights) decode(r *http.Request, v any) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		f.t.Errorf("unable to decode %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+func (f *fakeInsights) respond(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("unable to encode response: %v", err)
+	}
+}
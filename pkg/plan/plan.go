@@ -0,0 +1,420 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plan builds a reconciliation plan covering every resource type that
+// can be pushed to Insights, and applies a previously saved plan.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/diff"
+	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/models"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/policymappings"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+	"github.com/fairwindsops/insights-cli/pkg/teams"
+)
+
+// formatVersion is incremented when the plan file format changes
+// incompatibly.
+const formatVersion = 1
+
+// Directories locates the content of each resource type. All but Base are
+// relative to Base.
+type Directories struct {
+	Base            string
+	OPA             string
+	Rules           string
+	AppGroups       string
+	PolicyMappings  string
+	KyvernoPolicies string
+}
+
+// Plan holds the changes for every resource type. A nil resource type was not
+// present in the push directory and will not be changed.
+type Plan struct {
+	FormatVersion   int                  `json:"formatVersion"`
+	Organization    string               `json:"organization"`
+	CreatedAt       time.Time            `json:"createdAt"`
+	OPA             *opa.Plan            `json:"opa,omitempty"`
+	Rules           *rules.Plan          `json:"rules,omitempty"`
+	Settings        *policies.Plan       `json:"settings,omitempty"`
+	AppGroups       *appgroups.Plan      `json:"appGroups,omitempty"`
+	PolicyMappings  *policymappings.Plan `json:"policyMappings,omitempty"`
+	KyvernoPolicies *kyverno.Plan        `json:"kyvernoPolicies,omitempty"`
+	Teams           *teams.Plan          `json:"teams,omitempty"`
+}
+
+// Resource types, as named in plan and state files.
+const (
+	ResourceOPA             = "opa"
+	ResourceRules           = "rules"
+	ResourceSettings        = "settings"
+	ResourceAppGroups       = "appGroups"
+	ResourcePolicyMappings  = "policyMappings"
+	ResourceKyvernoPolicies = "kyvernoPolicies"
+	ResourceTeams           = "teams"
+)
+
+// ResourceTypes lists every resource type, in the order they are pushed.
+var ResourceTypes = []string{ResourceOPA, ResourceRules, ResourceSettings, ResourceAppGroups, ResourcePolicyMappings, ResourceKyvernoPolicies, ResourceTeams}
+
+// Path returns the location of the content of resourceType.
+func (d Directories) Path(resourceType string) string {
+	switch resourceType {
+	case ResourceOPA:
+		return filepath.Join(d.Base, d.OPA)
+	case ResourceRules:
+		return filepath.Join(d.Base, d.Rules)
+	case ResourceSettings:
+		return filepath.Join(d.Base, "settings.yaml")
+	case ResourceAppGroups:
+		return filepath.Join(d.Base, d.AppGroups)
+	case ResourcePolicyMappings:
+		return filepath.Join(d.Base, d.PolicyMappings)
+	case ResourceKyvernoPolicies:
+		return filepath.Join(d.Base, d.KyvernoPolicies)
+	case ResourceTeams:
+		return filepath.Join(d.Base, "teams.yaml")
+	}
+	return ""
+}
+
+// resourceDescriptions are used in log and error messages.
+var resourceDescriptions = map[string]string{
+	ResourceOPA:             "OPA policies",
+	ResourceRules:           "automation rules",
+	ResourceSettings:        "policies configuration",
+	ResourceAppGroups:       "app-groups",
+	ResourcePolicyMappings:  "policy-mappings",
+	ResourceKyvernoPolicies: "Kyverno policies",
+	ResourceTeams:           "teams",
+}
+
+// Build compares the content of dirs with Insights and returns a plan of the
+// changes needed to make Insights match dirs. When an owner is set, only
+// resources recorded in st as pushed with it are planned to be deleted.
+func Build(client *req.Client, org string, dirs Directories, deleteMissing bool, regoVersion string, st *state.State) (*Plan, error) {
+	p := Plan{FormatVersion: formatVersion, Organization: org, CreatedAt: time.Now().UTC()}
+	for _, resourceType := range ResourceTypes {
+		if !resourceExists(dirs.Path(resourceType), resourceDescriptions[resourceType]) {
+			continue
+		}
+		ownership.SetOwned(st.OwnedBy(ownership.Owner(), resourceType))
+		err := buildResource(client, org, dirs, resourceType, deleteMissing, regoVersion, &p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// buildResource sets the changes for resourceType in p.
+func buildResource(client *req.Client, org string, dirs Directories, resourceType string, deleteMissing bool, regoVersion string, p *Plan) error {
+	var err error
+	dir := dirs.Path(resourceType)
+	switch resourceType {
+	case ResourceOPA:
+		p.OPA, err = opa.BuildPlan(client, dir, org, deleteMissing, regoVersion)
+	case ResourceRules:
+		p.Rules, err = rules.BuildPlan(client, dir, org, deleteMissing)
+	case ResourceSettings:
+		p.Settings, err = policies.BuildPlan(client, dirs.Base, org)
+	case ResourceAppGroups:
+		p.AppGroups, err = appgroups.BuildPlan(client, dir, org, deleteMissing)
+	case ResourcePolicyMappings:
+		p.PolicyMappings, err = policymappings.BuildPlan(client, dir, org, deleteMissing)
+	case ResourceKyvernoPolicies:
+		var kyvernoPolicies []kyverno.KyvernoPolicy
+		kyvernoPolicies, err = kyverno.GetPolicyFilesForPush(dir)
+		if err != nil {
+			return fmt.Errorf("unable to read Kyverno policy files: %w", err)
+		}
+		p.KyvernoPolicies, err = kyverno.BuildPlan(client, kyvernoPolicies, org, deleteMissing)
+	case ResourceTeams:
+		p.Teams, err = teams.BuildPlan(client, dirs.Base, org, deleteMissing)
+	default:
+		return fmt.Errorf("unknown resource type %s", resourceType)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to plan %s: %w", resourceDescriptions[resourceType], err)
+	}
+	return nil
+}
+
+// resourceExists returns false, and logs that the resource type is skipped,
+// if the content at path does not exist.
+func resourceExists(path, description string) bool {
+	if !exists(path) {
+		logrus.Infof("Skipping %s, %s not found", description, path)
+		return false
+	}
+	return true
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Verify returns an error if any resource type covered by the plan has
+// changed in Insights since the plan was built.
+func Verify(client *req.Client, org string, p Plan) error {
+	if p.FormatVersion != formatVersion {
+		return fmt.Errorf("plan format version %d is not supported, expected %d", p.FormatVersion, formatVersion)
+	}
+	if p.Organization != org {
+		return fmt.Errorf("plan was created for organization %s, not %s", p.Organization, org)
+	}
+	if p.OPA != nil {
+		if err := opa.VerifyPlan(client, org, *p.OPA); err != nil {
+			return err
+		}
+	}
+	if p.Rules != nil {
+		if err := rules.VerifyPlan(client, org, *p.Rules); err != nil {
+			return err
+		}
+	}
+	if p.Settings != nil {
+		if err := policies.VerifyPlan(client, org, *p.Settings); err != nil {
+			return err
+		}
+	}
+	if p.AppGroups != nil {
+		if err := appgroups.VerifyPlan(client, org, *p.AppGroups); err != nil {
+			return err
+		}
+	}
+	if p.PolicyMappings != nil {
+		if err := policymappings.VerifyPlan(client, org, *p.PolicyMappings); err != nil {
+			return err
+		}
+	}
+	if p.KyvernoPolicies != nil {
+		if err := kyverno.VerifyPlan(client, org, *p.KyvernoPolicies); err != nil {
+			return err
+		}
+	}
+	if p.Teams != nil {
+		if err := teams.VerifyPlan(client, org, *p.Teams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply verifies that Insights has not changed since the plan was built, then
+// makes the changes described by the plan. Nothing is changed if
+// verification fails.
+func Apply(client *req.Client, org string, p Plan) error {
+	err := Verify(client, org, p)
+	if err != nil {
+		return fmt.Errorf("refusing to apply a stale plan: %w", err)
+	}
+	for _, resourceType := range ResourceTypes {
+		err := applyResource(client, org, p, resourceType, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyResource makes the changes for resourceType described by p, if p
+// covers resourceType.
+func applyResource(client *req.Client, org string, p Plan, resourceType string, dryRun bool) error {
+	var err error
+	switch {
+	case resourceType == ResourceOPA && p.OPA != nil:
+		err = opa.ApplyPlan(client, org, *p.OPA, dryRun)
+	case resourceType == ResourceRules && p.Rules != nil:
+		err = rules.ApplyPlan(client, org, *p.Rules, dryRun)
+	case resourceType == ResourceSettings && p.Settings != nil:
+		err = policies.ApplyPlan(client, org, *p.Settings, dryRun)
+	case resourceType == ResourceAppGroups && p.AppGroups != nil:
+		err = appgroups.ApplyPlan(client, org, *p.AppGroups, dryRun)
+	case resourceType == ResourcePolicyMappings && p.PolicyMappings != nil:
+		err = policymappings.ApplyPlan(client, org, *p.PolicyMappings, dryRun)
+	case resourceType == ResourceKyvernoPolicies && p.KyvernoPolicies != nil:
+		err = kyverno.ApplyPlan(client, org, *p.KyvernoPolicies, dryRun)
+	case resourceType == ResourceTeams && p.Teams != nil:
+		err = teams.ApplyPlan(client, org, *p.Teams, dryRun)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to apply %s: %w", resourceDescriptions[resourceType], err)
+	}
+	return nil
+}
+
+// Save writes the plan as JSON to fileName.
+func Save(p Plan, fileName string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, b, 0644)
+}
+
+// Load reads a plan previously written by Save.
+func Load(fileName string) (*Plan, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse plan %s: %w", fileName, err)
+	}
+	return &p, nil
+}
+
+// Print writes a human-readable summary of the plan to w, followed by a diff
+// of each updated resource.
+func Print(w io.Writer, p Plan) error {
+	if p.OPA != nil {
+		c := p.OPA.Changes
+		printChanges(w, "OPA policies",
+			checkNames(c.CheckInsert), checkNames(c.CheckUpdate), checkNames(c.CheckDelete))
+		printChanges(w, "OPA policy instances",
+			instanceNames(c.InstanceInsert), instanceNames(c.InstanceUpdate), instanceNames(c.InstanceDelete))
+	}
+	if p.Rules != nil {
+		printChanges(w, "automation rules",
+			ruleNames(p.Rules.RuleInsert), ruleNames(p.Rules.RuleUpdate), ruleNames(p.Rules.RuleDelete))
+	}
+	if p.Settings != nil {
+		if p.Settings.Changed {
+			fmt.Fprintln(w, "policies configuration: settings.yaml has changed and will be submitted")
+		} else {
+			fmt.Fprintln(w, "policies configuration: settings.yaml is unchanged and will be submitted")
+		}
+	}
+	if p.AppGroups != nil {
+		printChanges(w, "app-groups", nil, appGroupNames(p.AppGroups.Upserts), appGroupNames(p.AppGroups.Deletes))
+	}
+	if p.PolicyMappings != nil {
+		printChanges(w, "policy-mappings", nil, policyMappingNames(p.PolicyMappings.Upserts), policyMappingNames(p.PolicyMappings.Deletes))
+	}
+	if p.KyvernoPolicies != nil {
+		printChanges(w, "Kyverno policies", p.KyvernoPolicies.Inserts, p.KyvernoPolicies.Updates, p.KyvernoPolicies.Deletes)
+	}
+	if p.Teams != nil {
+		printChanges(w, "teams", p.Teams.Inserts, p.Teams.Updates, p.Teams.Deletes)
+	}
+	return diff.Print(w, diffs(p))
+}
+
+// HasChanges returns true if applying the plan would change any resource in
+// Insights, meaning Insights has drifted from the content the plan was built
+// from.
+func HasChanges(p Plan) bool {
+	if p.OPA != nil {
+		c := p.OPA.Changes
+		if len(c.CheckInsert)+len(c.CheckUpdate)+len(c.CheckDelete)+len(c.InstanceInsert)+len(c.InstanceUpdate)+len(c.InstanceDelete) > 0 {
+			return true
+		}
+	}
+	if p.Rules != nil && len(p.Rules.RuleInsert)+len(p.Rules.RuleUpdate)+len(p.Rules.RuleDelete) > 0 {
+		return true
+	}
+	if p.Settings != nil && p.Settings.Changed {
+		return true
+	}
+	if p.AppGroups != nil && len(p.AppGroups.Upserts)+len(p.AppGroups.Deletes) > 0 {
+		return true
+	}
+	if p.PolicyMappings != nil && len(p.PolicyMappings.Upserts)+len(p.PolicyMappings.Deletes) > 0 {
+		return true
+	}
+	if p.KyvernoPolicies != nil && len(p.KyvernoPolicies.Inserts)+len(p.KyvernoPolicies.Updates)+len(p.KyvernoPolicies.Deletes) > 0 {
+		return true
+	}
+	if p.Teams != nil && len(p.Teams.Inserts)+len(p.Teams.Updates)+len(p.Teams.Deletes) > 0 {
+		return true
+	}
+	return false
+}
+
+// diffs returns the content changes of every updated resource in the plan.
+func diffs(p Plan) []diff.Diff {
+	var d []diff.Diff
+	if p.OPA != nil {
+		d = append(d, p.OPA.Changes.Diffs...)
+	}
+	if p.Rules != nil {
+		d = append(d, p.Rules.Diffs...)
+	}
+	if p.Settings != nil {
+		d = append(d, p.Settings.Diffs...)
+	}
+	if p.AppGroups != nil {
+		d = append(d, p.AppGroups.Diffs...)
+	}
+	if p.PolicyMappings != nil {
+		d = append(d, p.PolicyMappings.Diffs...)
+	}
+	return d
+}
+
+func printChanges(w io.Writer, resourceType string, inserts, updates, deletes []string) {
+	fmt.Fprintf(w, "%s: %d to add, %d to change, %d to delete\n", resourceType, len(inserts), len(updates), len(deletes))
+	for _, name := range inserts {
+		fmt.Fprintf(w, "  + %s\n", name)
+	}
+	for _, name := range updates {
+		fmt.Fprintf(w, "  ~ %s\n", name)
+	}
+	for _, name := range deletes {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+}
+
+func checkNames(checks []models.CustomCheckModel) []string {
+	return lo.Map(checks, func(c models.CustomCheckModel, _ int) string { return c.CheckName })
+}
+
+func instanceNames(instances []models.CustomCheckInstanceModel) []string {
+	return lo.Map(instances, func(i models.CustomCheckInstanceModel, _ int) string {
+		return i.CheckName + "/" + i.InstanceName
+	})
+}
+
+func ruleNames(r []rules.Rule) []string {
+	return lo.Map(r, func(rule rules.Rule, _ int) string { return rule.Name })
+}
+
+func appGroupNames(a []appgroups.AppGroup) []string {
+	return lo.Map(a, func(appGroup appgroups.AppGroup, _ int) string { return appGroup.Name })
+}
+
+func policyMappingNames(p []policymappings.PolicyMapping) []string {
+	return lo.Map(p, func(policyMapping policymappings.PolicyMapping, _ int) string { return policyMapping.Name })
+}
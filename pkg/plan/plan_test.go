@@ -0,0 +1,212 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+	"github.com/fairwindsops/insights-cli/pkg/teams"
+)
+
+func TestHasChanges(t *testing.T) {
+	assert.False(t, HasChanges(Plan{}))
+	assert.False(t, HasChanges(Plan{
+		OPA:       &opa.Plan{},
+		Settings:  &policies.Plan{Settings: "checks: {}"},
+		AppGroups: &appgroups.Plan{},
+		Teams:     &teams.Plan{Teams: []teams.TeamInput{{Name: "platform"}}},
+	}))
+	assert.True(t, HasChanges(Plan{Settings: &policies.Plan{Changed: true}}))
+	assert.True(t, HasChanges(Plan{AppGroups: &appgroups.Plan{Deletes: []appgroups.AppGroup{{Name: "web"}}}}))
+	assert.True(t, HasChanges(Plan{Teams: &teams.Plan{Updates: []string{"platform"}}}))
+}
+
+func TestPushSkipsUnchanged(t *testing.T) {
+	dirs := Directories{Base: t.TempDir(), Rules: "rules"}
+	assert.NoError(t, os.MkdirAll(dirs.Path(ResourceRules), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dirs.Path(ResourceRules), "rule.yaml"), []byte("name: rule"), 0644))
+	hash, err := localHash(dirs, ResourceRules, false, "")
+	assert.NoError(t, err)
+	st := state.New("https://insights.fairwinds.com", "acme-co")
+	st.Record(ResourceRules, hash, "remote")
+
+	// the nil client is not used, as the rules are unchanged since the last push
+	err = Push(nil, "acme-co", dirs, ResourceRules, false, false, "", st, false)
+	assert.NoError(t, err)
+}
+
+// newTestPlan returns a plan of dirs that inserts one rule and upserts one
+// app-group, and deletes the rule and app-group that are only in Insights.
+func newTestPlan(t *testing.T) (*fakeInsights, Directories, *Plan, func() *Plan) {
+	f, client := newFakeInsights(t)
+	f.rules = []rules.Rule{{ID: 1, Name: "old", Context: "Agent", Action: "action.set('Severity', 0.1)"}}
+	f.appGroups = []appgroups.AppGroup{{Name: "stale", Type: "AppGroup"}}
+	dirs := Directories{Base: t.TempDir(), OPA: "opa", Rules: "rules", AppGroups: "app-groups", PolicyMappings: "policy-mappings", KyvernoPolicies: "kyverno-policies"}
+	assert.NoError(t, os.MkdirAll(dirs.Path(ResourceRules), 0755))
+	assert.NoError(t, os.MkdirAll(dirs.Path(ResourceAppGroups), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dirs.Path(ResourceRules), "new.yaml"),
+		[]byte("name: new\ncontext: Agent\naction: action.set('Severity', 0.9)\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dirs.Path(ResourceAppGroups), "web.yaml"),
+		[]byte("name: web\ntype: AppGroup\nspec:\n  match:\n  - namespaces: [web]\n"), 0644))
+	build := func() *Plan {
+		p, err := Build(client, "acme-co", dirs, true, "", state.New("https://insights.fairwinds.com", "acme-co"))
+		assert.NoError(t, err)
+		return p
+	}
+	return f, dirs, build(), build
+}
+
+func TestSaveLoad(t *testing.T) {
+	_, _, p, _ := newTestPlan(t)
+	fileName := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, Save(*p, fileName))
+	loaded, err := Load(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, p, loaded)
+	assert.Len(t, loaded.Rules.RuleInsert, 1)
+	assert.Len(t, loaded.Rules.RuleDelete, 1)
+	assert.Len(t, loaded.AppGroups.Upserts, 1)
+	assert.Len(t, loaded.AppGroups.Deletes, 1)
+}
+
+func TestApplyOrdering(t *testing.T) {
+	f, _, p, rebuild := newTestPlan(t)
+	fileName := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, Save(*p, fileName))
+	loaded, err := Load(fileName)
+	assert.NoError(t, err)
+
+	deletion.SetAssumeYes(true)
+	t.Cleanup(func() { deletion.SetAssumeYes(false) })
+	assert.NoError(t, Apply(f.client(), "acme-co", *loaded))
+	// resource types are applied in order, and deletes follow upserts
+	assert.Equal(t, []string{
+		"POST /v0/organizations/acme-co/rules/create",
+		"DELETE /v0/organizations/acme-co/rules/1",
+		"POST /v0/organizations/acme-co/app-groups",
+		"DELETE /v0/organizations/acme-co/app-groups/stale",
+	}, f.Writes())
+	assert.False(t, HasChanges(*rebuild()))
+}
+
+func TestApplyUpdatesAppGroup(t *testing.T) {
+	f, _, _, rebuild := newTestPlan(t)
+	f.appGroups = append(f.appGroups, appgroups.AppGroup{Name: "web", Type: "AppGroup"})
+	deletion.SetAssumeYes(true)
+	t.Cleanup(func() { deletion.SetAssumeYes(false) })
+
+	p := rebuild()
+	assert.Len(t, p.AppGroups.Upserts, 1)
+	assert.NoError(t, Apply(f.client(), "acme-co", *p))
+	assert.Equal(t, []string{"web"}, appGroupNames(f.appGroups),
+		"the existing app-group is updated rather than added again")
+	assert.False(t, HasChanges(*rebuild()))
+}
+
+func TestApplyWithSelectorKeepsResourcesOnlyInInsights(t *testing.T) {
+	f, dirs, p, rebuild := newTestPlan(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dirs.Path(ResourceRules), "labeled.yaml"),
+		[]byte("name: labeled\ncontext: Agent\naction: action.set('Severity', 0.5)\nlabels:\n  team: web\n"), 0644))
+	selector, err := filter.New(nil, nil, "team=web")
+	assert.NoError(t, err)
+	filter.Set(selector)
+	t.Cleanup(func() { filter.Set(filter.Filter{}) })
+
+	p = rebuild()
+	assert.Empty(t, p.Rules.RuleDelete)
+	assert.Empty(t, p.AppGroups.Deletes)
+	deletion.SetAssumeYes(true)
+	t.Cleanup(func() { deletion.SetAssumeYes(false) })
+	assert.NoError(t, Apply(f.client(), "acme-co", *p))
+	assert.Equal(t, []string{"POST /v0/organizations/acme-co/rules/create"}, f.Writes(),
+		"only the selected rule is pushed, and the rule and app-group only in Insights are kept")
+}
+
+func TestPushWithOwnerOnlyDeletesOwnedResources(t *testing.T) {
+	f, dirs, _, _ := newTestPlan(t)
+	ownership.SetOwner("acme/policies")
+	t.Cleanup(func() {
+		ownership.SetOwner("")
+		ownership.SetOwned(nil)
+	})
+	deletion.SetAssumeYes(true)
+	t.Cleanup(func() { deletion.SetAssumeYes(false) })
+	st := state.New("https://insights.fairwinds.com", "acme-co")
+
+	assert.NoError(t, Push(f.client(), "acme-co", dirs, ResourceRules, true, false, "", st, false))
+	assert.Equal(t, []string{"POST /v0/organizations/acme-co/rules/create"}, f.Writes(),
+		"the rule only in Insights was not pushed with the owner, and is kept")
+	assert.Equal(t, []string{"new"}, st.OwnedBy("acme/policies", ResourceRules))
+
+	assert.NoError(t, os.Remove(filepath.Join(dirs.Path(ResourceRules), "new.yaml")))
+	assert.NoError(t, Push(f.client(), "acme-co", dirs, ResourceRules, true, false, "", st, false))
+	assert.Equal(t, []string{
+		"POST /v0/organizations/acme-co/rules/create",
+		"DELETE /v0/organizations/acme-co/rules/101",
+	}, f.Writes(), "the rule pushed with the owner is deleted once removed from the push directory")
+	assert.Empty(t, st.OwnedBy("acme/policies", ResourceRules))
+}
+
+func TestPushOPAOutputSettings(t *testing.T) {
+	f, client := newFakeInsights(t)
+	dirs := Directories{Base: t.TempDir(), OPA: "opa"}
+	checkDir := filepath.Join(dirs.Path(ResourceOPA), "needs-label")
+	assert.NoError(t, os.MkdirAll(checkDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(checkDir, "policy.rego"), []byte("package fairwinds\n"), 0644))
+	writeSettings := func(title string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(checkDir, ".policy-settings.yaml"), []byte("output:\n  title: "+title+"\n"), 0644))
+	}
+	st := state.New("https://insights.fairwinds.com", "acme-co")
+
+	writeSettings("Missing label")
+	assert.NoError(t, Push(client, "acme-co", dirs, ResourceOPA, false, false, "v0", st, true))
+	writeSettings("Label is missing")
+	assert.NoError(t, Push(client, "acme-co", dirs, ResourceOPA, false, false, "v0", st, true))
+	assert.Len(t, f.Writes(), 2, "the edited output is pushed")
+	assert.Equal(t, "Label is missing", *f.checks[0].Title)
+
+	assert.NoError(t, Push(client, "acme-co", dirs, ResourceOPA, false, false, "v0", st, true))
+	assert.Len(t, f.Writes(), 2, "a second push of the edited output makes no changes")
+}
+
+func TestApplyRefusesStalePlan(t *testing.T) {
+	f, _, p, _ := newTestPlan(t)
+	f.appGroups = append(f.appGroups, appgroups.AppGroup{Name: "added-since", Type: "AppGroup"})
+
+	err := Apply(f.client(), "acme-co", *p)
+	assert.ErrorContains(t, err, "refusing to apply a stale plan")
+	assert.ErrorContains(t, err, "app-groups in Insights have changed since the plan was created")
+	assert.Empty(t, f.Writes())
+}
+
+func TestVerify(t *testing.T) {
+	f, _, p, _ := newTestPlan(t)
+	assert.NoError(t, Verify(f.client(), "acme-co", *p))
+	assert.ErrorContains(t, Verify(f.client(), "other-co", *p), "plan was created for organization acme-co, not other-co")
+	stale := *p
+	stale.FormatVersion = formatVersion + 1
+	assert.ErrorContains(t, Verify(f.client(), "acme-co", stale), "is not supported")
+}
+
+func TestVerifyStaleSettings(t *testing.T) {
+	f, dirs, _, rebuild := newTestPlan(t)
+	f.settings = "checks:\n  polaris: {}\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dirs.Base, "settings.yaml"), []byte("checks: {}\n"), 0644))
+	p := rebuild()
+	assert.NoError(t, Verify(f.client(), "acme-co", *p))
+
+	f.settings = "checks:\n  trivy: {}\n"
+	err := Apply(f.client(), "acme-co", *p)
+	assert.ErrorContains(t, err, "policies configuration in Insights has changed since the plan was created")
+	assert.Empty(t, f.Writes())
+}
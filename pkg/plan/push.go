@@ -0,0 +1,189 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/models"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/policymappings"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/state"
+	"github.com/fairwindsops/insights-cli/pkg/teams"
+)
+
+// Push plans and applies the changes for resourceType in dirs, and records
+// the push, and the resources owned after it, in st. Unless refresh is true, a resource type whose content and
+// push options are unchanged since the push recorded in st is skipped without
+// reading it from Insights. A warning is logged if the resource type has been
+// changed in Insights since that push.
+func Push(client *req.Client, org string, dirs Directories, resourceType string, deleteMissing, dryRun bool, regoVersion string, st *state.State, refresh bool) error {
+	description := resourceDescriptions[resourceType]
+	hash, err := localHash(dirs, resourceType, deleteMissing, regoVersion)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", description, err)
+	}
+	if !refresh && st.Unchanged(resourceType, hash) {
+		logrus.Infof("Skipping %s, unchanged since the last push. Use --refresh to compare with Insights anyway.", description)
+		return nil
+	}
+	p := Plan{FormatVersion: formatVersion, Organization: org, CreatedAt: time.Now().UTC()}
+	ownership.SetOwned(st.OwnedBy(ownership.Owner(), resourceType))
+	err = buildResource(client, org, dirs, resourceType, deleteMissing, regoVersion, &p)
+	if err != nil {
+		return err
+	}
+	remoteFingerprint := plannedFingerprint(p, resourceType)
+	if st.RemoteChanged(resourceType, remoteFingerprint) {
+		logrus.Warnf("%s in Insights were changed since the last push from %s, and may be overwritten", description, dirs.Base)
+	}
+	err = applyResource(client, org, p, resourceType, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	if HasChanges(p) || submitsUnchanged[resourceType] {
+		remoteFingerprint, err = currentFingerprint(client, org, p, resourceType)
+		if err != nil {
+			return fmt.Errorf("unable to record the state of %s after pushing: %w", description, err)
+		}
+	}
+	st.Record(resourceType, hash, remoteFingerprint)
+	if ownership.Owner() != "" {
+		recordOwned(st, p, resourceType)
+	}
+	return nil
+}
+
+// recordOwned records in st the resources of resourceType owned after p was
+// applied: those in the push directory, and those previously recorded that p
+// did not delete. Resource types whose deletions are not limited by ownership
+// are not recorded.
+func recordOwned(st *state.State, p Plan, resourceType string) {
+	var local, deleted []string
+	switch {
+	case resourceType == ResourceOPA && p.OPA != nil:
+		local = p.OPA.FileCheckNames
+		deleted = lo.Map(p.OPA.Changes.CheckDelete, func(c models.CustomCheckModel, _ int) string { return c.CheckName })
+	case resourceType == ResourceRules && p.Rules != nil:
+		local = p.Rules.FileRuleNames
+		deleted = lo.Map(p.Rules.RuleDelete, func(r rules.Rule, _ int) string { return r.Name })
+	case resourceType == ResourceAppGroups && p.AppGroups != nil:
+		local = p.AppGroups.FileAppGroupNames
+		deleted = lo.Map(p.AppGroups.Deletes, func(a appgroups.AppGroup, _ int) string { return a.Name })
+	case resourceType == ResourcePolicyMappings && p.PolicyMappings != nil:
+		local = p.PolicyMappings.FilePolicyMappingNames
+		deleted = lo.Map(p.PolicyMappings.Deletes, func(m policymappings.PolicyMapping, _ int) string { return m.Name })
+	default:
+		return
+	}
+	kept, _ := lo.Difference(st.OwnedBy(ownership.Owner(), resourceType), deleted)
+	st.RecordOwned(ownership.Owner(), resourceType, append(kept, local...))
+}
+
+// Pending returns true if Push would compare resourceType in dirs with
+// Insights, as it has changed since the push recorded in st or refresh is
+// true.
+func Pending(dirs Directories, resourceType string, deleteMissing bool, regoVersion string, st *state.State, refresh bool) (bool, error) {
+	if refresh {
+		return true, nil
+	}
+	hash, err := localHash(dirs, resourceType, deleteMissing, regoVersion)
+	if err != nil {
+		return false, fmt.Errorf("unable to read %s: %w", resourceDescriptions[resourceType], err)
+	}
+	return !st.Unchanged(resourceType, hash), nil
+}
+
+// localHash returns a hash of the content of resourceType, including its
+// overlay, and of the push options that change what is pushed.
+func localHash(dirs Directories, resourceType string, deleteMissing bool, regoVersion string) (string, error) {
+	overlayHash := ""
+	if overlayPath := overlay.Path(dirs.Path(resourceType)); overlayPath != "" && exists(overlayPath) {
+		var err error
+		overlayHash, err = state.HashPath(overlayPath)
+		if err != nil {
+			return "", err
+		}
+	}
+	return state.HashPath(dirs.Path(resourceType),
+		fmt.Sprintf("delete=%t", deleteMissing), "owner="+ownership.Owner(), "regoVersion="+regoVersion, "filter="+filter.String(), "overlay="+overlayHash)
+}
+
+// submitsUnchanged holds the resource types whose content is submitted to
+// Insights even when nothing has changed, which may change their fingerprint.
+var submitsUnchanged = map[string]bool{
+	ResourceSettings:        true,
+	ResourceKyvernoPolicies: true,
+	ResourceTeams:           true,
+}
+
+// plannedFingerprint returns the fingerprint of resourceType in Insights
+// recorded in p when it was built.
+func plannedFingerprint(p Plan, resourceType string) string {
+	switch {
+	case resourceType == ResourceOPA && p.OPA != nil:
+		return p.OPA.RemoteFingerprint
+	case resourceType == ResourceRules && p.Rules != nil:
+		return p.Rules.RemoteFingerprint
+	case resourceType == ResourceSettings && p.Settings != nil:
+		return p.Settings.RemoteFingerprint
+	case resourceType == ResourceAppGroups && p.AppGroups != nil:
+		return p.AppGroups.RemoteFingerprint
+	case resourceType == ResourcePolicyMappings && p.PolicyMappings != nil:
+		return p.PolicyMappings.RemoteFingerprint
+	case resourceType == ResourceKyvernoPolicies && p.KyvernoPolicies != nil:
+		return p.KyvernoPolicies.RemoteFingerprint
+	case resourceType == ResourceTeams && p.Teams != nil:
+		return p.Teams.RemoteFingerprint
+	}
+	return ""
+}
+
+// currentFingerprint reads resourceType from Insights and returns its
+// fingerprint, computed the same way as when p was built.
+func currentFingerprint(client *req.Client, org string, p Plan, resourceType string) (string, error) {
+	switch {
+	case resourceType == ResourceOPA && p.OPA != nil:
+		return opa.RemoteFingerprint(client, org, *p.OPA)
+	case resourceType == ResourceRules && p.Rules != nil:
+		return rules.RemoteFingerprint(client, org, *p.Rules)
+	case resourceType == ResourceSettings && p.Settings != nil:
+		return policies.RemoteFingerprint(client, org, *p.Settings)
+	case resourceType == ResourceAppGroups && p.AppGroups != nil:
+		return appgroups.RemoteFingerprint(client, org, *p.AppGroups)
+	case resourceType == ResourcePolicyMappings && p.PolicyMappings != nil:
+		return policymappings.RemoteFingerprint(client, org, *p.PolicyMappings)
+	case resourceType == ResourceKyvernoPolicies && p.KyvernoPolicies != nil:
+		return kyverno.RemoteFingerprint(client, org, *p.KyvernoPolicies)
+	case resourceType == ResourceTeams && p.Teams != nil:
+		return teams.RemoteFingerprint(client, org, *p.Teams)
+	}
+	return "", nil
+}
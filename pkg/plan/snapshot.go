@@ -0,0 +1,207 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	opaPlugin "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/opa"
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/kyverno"
+	"github.com/fairwindsops/insights-cli/pkg/opa"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/policies"
+	"github.com/fairwindsops/insights-cli/pkg/policymappings"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
+)
+
+// snapshotFormatVersion is incremented when the snapshot file format changes
+// incompatibly.
+const snapshotFormatVersion = 1
+
+// Snapshot holds the state of resource types in Insights. A nil resource type
+// was not captured, and is left alone when the snapshot is restored.
+type Snapshot struct {
+	FormatVersion   int                            `json:"formatVersion"`
+	Organization    string                         `json:"organization"`
+	CreatedAt       time.Time                      `json:"createdAt"`
+	OPA             *OPASnapshot                   `json:"opa"`
+	Rules           []rules.Rule                   `json:"rules"`
+	AppGroups       []appgroups.AppGroup           `json:"appGroups"`
+	PolicyMappings  []policymappings.PolicyMapping `json:"policyMappings"`
+	KyvernoPolicies []kyverno.KyvernoPolicy        `json:"kyvernoPolicies"`
+	Settings        *string                        `json:"settings"`
+}
+
+// OPASnapshot holds the OPA checks and instances in Insights, and the rego
+// version of each check by name.
+type OPASnapshot struct {
+	Checks       []opaPlugin.OPACustomCheck `json:"checks"`
+	Instances    []opaPlugin.CheckSetting   `json:"instances"`
+	RegoVersions map[string]string          `json:"regoVersions,omitempty"`
+}
+
+// TakeSnapshot captures the state in Insights of each of resourceTypes that
+// has content in dirs, which are the resource types a push of dirs would
+// change.
+func TakeSnapshot(client *req.Client, org string, dirs Directories, resourceTypes []string) (*Snapshot, error) {
+	s := Snapshot{FormatVersion: snapshotFormatVersion, Organization: org, CreatedAt: time.Now().UTC()}
+	captured := func(resourceType string) bool {
+		return lo.Contains(resourceTypes, resourceType) && exists(dirs.Path(resourceType))
+	}
+	if captured(ResourceOPA) {
+		checks, err := opa.GetChecks(client, org)
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot OPA policies: %w", err)
+		}
+		instances, err := workerpool.Map(workerpool.Concurrency(), checks, func(check opaPlugin.OPACustomCheck) ([]opaPlugin.CheckSetting, error) {
+			return opa.GetInstances(client, org, check.Name)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot instances of OPA policies: %w", err)
+		}
+		regoVersions := lo.SliceToMap(checks, func(check opaPlugin.OPACustomCheck) (string, string) {
+			return check.Name, opa.DetectRegoVersion(check.Rego)
+		})
+		s.OPA = &OPASnapshot{Checks: nonNil(checks), Instances: nonNil(lo.Flatten(instances)), RegoVersions: regoVersions}
+	}
+	if captured(ResourceRules) {
+		existingRules, err := rules.FetchRules(client, org)
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot automation rules: %w", err)
+		}
+		s.Rules = nonNil(existingRules)
+	}
+	if captured(ResourceSettings) {
+		settings, err := policies.GetPolicies(client, org)
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot policies configuration: %w", err)
+		}
+		s.Settings = lo.ToPtr(string(settings))
+	}
+	if captured(ResourceAppGroups) {
+		appGroups, err := appgroups.FetchAppGroups(client, org)
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot app-groups: %w", err)
+		}
+		s.AppGroups = nonNil(appGroups)
+	}
+	if captured(ResourcePolicyMappings) {
+		policyMappings, err := policymappings.FetchPolicyMappings(client, org)
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot policy-mappings: %w", err)
+		}
+		s.PolicyMappings = nonNil(policyMappings)
+	}
+	if captured(ResourceKyvernoPolicies) {
+		kyvernoPolicies, err := kyverno.FetchKyvernoPolicies(client, org)
+		if err != nil {
+			return nil, fmt.Errorf("unable to snapshot Kyverno policies: %w", err)
+		}
+		s.KyvernoPolicies = nonNil(kyvernoPolicies)
+	}
+	return &s, nil
+}
+
+// nonNil returns an empty slice in place of nil, so an empty resource type is
+// told apart from one that was not captured.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// BuildRestore returns a plan of the changes needed to make Insights match
+// the snapshot. Resources created since the snapshot was taken are deleted.
+// OPA checks whose rego version the snapshot does not record are restored
+// with regoVersion. Deletions are not limited by ownership, as the snapshot
+// holds every resource.
+func BuildRestore(client *req.Client, org string, s Snapshot, regoVersion string) (*Plan, error) {
+	defer ownership.SetOwner(ownership.Owner())
+	ownership.SetOwner("")
+	if s.FormatVersion != snapshotFormatVersion {
+		return nil, fmt.Errorf("snapshot format version %d is not supported, expected %d", s.FormatVersion, snapshotFormatVersion)
+	}
+	if s.Organization != org {
+		return nil, fmt.Errorf("snapshot was taken of organization %s, not %s", s.Organization, org)
+	}
+	p := Plan{FormatVersion: formatVersion, Organization: org, CreatedAt: time.Now().UTC()}
+	var err error
+	if s.OPA != nil {
+		p.OPA, err = opa.BuildRestorePlan(client, org, s.OPA.Checks, s.OPA.Instances, s.OPA.RegoVersions, regoVersion)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan restore of OPA policies: %w", err)
+		}
+	}
+	if s.Rules != nil {
+		p.Rules, err = rules.BuildRestorePlan(client, org, s.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan restore of automation rules: %w", err)
+		}
+	}
+	if s.AppGroups != nil {
+		p.AppGroups, err = appgroups.BuildRestorePlan(client, org, s.AppGroups)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan restore of app-groups: %w", err)
+		}
+	}
+	if s.PolicyMappings != nil {
+		p.PolicyMappings, err = policymappings.BuildRestorePlan(client, org, s.PolicyMappings)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan restore of policy-mappings: %w", err)
+		}
+	}
+	if s.KyvernoPolicies != nil {
+		p.KyvernoPolicies, err = kyverno.BuildPlan(client, s.KyvernoPolicies, org, true)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan restore of Kyverno policies: %w", err)
+		}
+	}
+	if s.Settings != nil {
+		p.Settings = &policies.Plan{Settings: *s.Settings, Changed: true}
+	}
+	return &p, nil
+}
+
+// SaveSnapshot writes the snapshot as JSON to fileName.
+func SaveSnapshot(s Snapshot, fileName string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, b, 0644)
+}
+
+// LoadSnapshot reads a snapshot previously written by SaveSnapshot.
+func LoadSnapshot(fileName string) (*Snapshot, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse snapshot %s: %w", fileName, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,81 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	opaPlugin "github.com/fairwindsops/insights-plugins/plugins/opa/pkg/opa"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fairwindsops/insights-cli/pkg/appgroups"
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/rules"
+)
+
+const regoV0 = `package fairwinds
+
+deny[actionItem] {
+	actionItem := {"title": "v0"}
+}
+`
+
+const regoV1 = `package fairwinds
+
+deny contains actionItem if {
+	actionItem := {"title": "v1"}
+}
+`
+
+func TestTakeSnapshotBuildRestore(t *testing.T) {
+	f, client := newFakeInsights(t)
+	f.checks = []opaPlugin.OPACustomCheck{
+		{Name: "old-style", Version: 2, Rego: regoV0},
+		{Name: "new-style", Version: 2, Rego: regoV1},
+	}
+	f.rules = []rules.Rule{{ID: 1, Name: "kept", Context: "Agent", Action: "action.set('Severity', 0.1)"}}
+	f.appGroups = []appgroups.AppGroup{{Name: "web", Type: "AppGroup"}}
+	dirs := Directories{Base: t.TempDir(), OPA: "opa", Rules: "rules", AppGroups: "app-groups", PolicyMappings: "policy-mappings", KyvernoPolicies: "kyverno-policies"}
+	for _, resourceType := range []string{ResourceOPA, ResourceRules, ResourceAppGroups} {
+		assert.NoError(t, os.MkdirAll(dirs.Path(resourceType), 0755))
+	}
+
+	snapshot, err := TakeSnapshot(client, "acme-co", dirs, ResourceTypes)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"old-style": "v0", "new-style": "v1"}, snapshot.OPA.RegoVersions)
+	assert.Nil(t, snapshot.PolicyMappings, "policy-mappings are not in the push directory")
+	fileName := filepath.Join(t.TempDir(), "snapshot.json")
+	assert.NoError(t, SaveSnapshot(*snapshot, fileName))
+	snapshot, err = LoadSnapshot(fileName)
+	assert.NoError(t, err)
+
+	// a push that removed everything captured in the snapshot, and added more
+	f.checks = nil
+	f.rules = []rules.Rule{{ID: 2, Name: "added", Context: "Agent", Action: "action.set('Severity', 0.9)"}}
+	f.appGroups = nil
+
+	p, err := BuildRestore(client, "acme-co", *snapshot, "v0")
+	assert.NoError(t, err)
+	deletion.SetAssumeYes(true)
+	t.Cleanup(func() { deletion.SetAssumeYes(false) })
+	assert.NoError(t, Apply(client, "acme-co", *p))
+
+	assert.ElementsMatch(t, []string{"old-style", "new-style"}, checkNamesOf(f.checks))
+	assert.Equal(t, map[string]string{"old-style": "v0", "new-style": "v1"}, f.regoVersions)
+	assert.Equal(t, []string{"kept"}, ruleNames(f.rules))
+	assert.Equal(t, []string{"web"}, appGroupNames(f.appGroups))
+}
+
+func TestBuildRestoreRejectsOtherOrganization(t *testing.T) {
+	_, client := newFakeInsights(t)
+	_, err := BuildRestore(client, "other-co", Snapshot{FormatVersion: snapshotFormatVersion, Organization: "acme-co"}, "v0")
+	assert.EqualError(t, err, "snapshot was taken of organization acme-co, not other-co")
+}
+
+func checkNamesOf(checks []opaPlugin.OPACustomCheck) []string {
+	var names []string
+	for _, c := range checks {
+		names = append(names, c.Name)
+	}
+	return names
+}
@@ -20,9 +20,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/imroc/req/v3"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/fairwindsops/insights-cli/pkg/diff"
+	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/variables"
 )
 
 const policiesPutURLFormat = "/v0/organizations/%s/policies"
@@ -45,34 +52,113 @@ func PutPolicies(client *req.Client, policies io.Reader, org string) error {
 	return nil
 }
 
-// PushPolicies verifies the policies settings file is readable, then pushes
-// it to the Insights API.
-func PushPolicies(client *req.Client, pushDir, org string, dryRun bool) error {
-	if pushDir == "" {
-		return errors.New("pushDir cannot be empty")
+// GetPolicies returns the policies configuration in Insights, as YAML, from
+// the endpoint PutPolicies submits to.
+func GetPolicies(client *req.Client, org string) ([]byte, error) {
+	url := fmt.Sprintf(policiesPutURLFormat, org)
+	resp, err := client.R().SetHeaders(getHeaders()).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsErrorState() {
+		return nil, fmt.Errorf("invalid HTTP response %d %s", resp.StatusCode, string(resp.Bytes()))
 	}
-	policiesFileName := pushDir + "/settings.yaml"
-	logrus.Infof("Pushing policies configuration from %s", policiesFileName)
-	_, err := os.Stat(policiesFileName)
+	return resp.Bytes(), nil
+}
+
+// DownloadPolicies writes the policies configuration in Insights to
+// settings.yaml in saveDir, which BuildPlan reads back.
+func DownloadPolicies(client *req.Client, org, saveDir string) error {
+	b, err := GetPolicies(client, org)
 	if err != nil {
 		return err
 	}
-	if dryRun {
-		logrus.Infoln("NOTE: The policies configuration is currently validated at the time it is submitted to Insights. Pushing this configuration in dry-run mode only validates the file is readable.")
-		return nil
+	return os.WriteFile(saveDir+"/settings.yaml", b, 0644)
+}
+
+// Plan holds the policies configuration that will be submitted by ApplyPlan.
+// The policies configuration is validated by Insights when it is submitted,
+// so it is always submitted. Changed reports whether it differs from the
+// policies configuration in Insights.
+type Plan struct {
+	Settings          string      `json:"settings"`
+	Changed           bool        `json:"changed"`
+	Diffs             []diff.Diff `json:"diffs"`
+	RemoteFingerprint string      `json:"remoteFingerprint"`
+}
+
+// BuildPlan reads the settings.yaml file in pushDir, and compares it with the
+// policies configuration in Insights.
+func BuildPlan(client *req.Client, pushDir, org string) (*Plan, error) {
+	if pushDir == "" {
+		return nil, errors.New("pushDir cannot be empty")
 	}
-	policies, err := os.Open(policiesFileName)
+	b, err := variables.ReadFile(pushDir + "/settings.yaml")
 	if err != nil {
-		return err
+		return nil, err
+	}
+	remote, err := GetPolicies(client, org)
+	if err != nil {
+		return nil, fmt.Errorf("error getting policies configuration: %w", err)
 	}
-	err = PutPolicies(client, policies, org)
+	fingerprint, err := utils.Fingerprint(string(remote))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint policies configuration: %w", err)
+	}
+	plan := Plan{Settings: string(b), RemoteFingerprint: fingerprint}
+	var localSettings, remoteSettings any
+	err = yaml.Unmarshal(b, &localSettings)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse settings.yaml: %w", err)
+	}
+	err = yaml.Unmarshal(remote, &remoteSettings)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse policies configuration from Insights: %w", err)
+	}
+	if !reflect.DeepEqual(localSettings, remoteSettings) {
+		plan.Changed = true
+		d, err := diff.YAML("settings", "settings", remoteSettings, localSettings)
+		if err != nil {
+			return nil, fmt.Errorf("unable to diff policies configuration: %w", err)
+		}
+		plan.Diffs = append(plan.Diffs, d)
+	}
+	return &plan, nil
+}
+
+// RemoteFingerprint returns the fingerprint of the policies configuration in
+// Insights, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	remote, err := GetPolicies(client, org)
+	if err != nil {
+		return "", fmt.Errorf("error getting policies configuration: %w", err)
+	}
+	return utils.Fingerprint(string(remote))
+}
+
+// VerifyPlan returns an error if the policies configuration in Insights has
+// changed since the plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
 	if err != nil {
 		return err
 	}
-	logrus.Debugln("Done pushing policies configuration")
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("policies configuration in Insights has changed since the plan was created")
+	}
 	return nil
 }
 
+// ApplyPlan submits the policies configuration described by the plan to
+// Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	logrus.Infoln("Submitting policies configuration")
+	if dryRun {
+		return nil
+	}
+	return PutPolicies(client, strings.NewReader(plan.Settings), org)
+}
+
 // getHeaders returns headers to be used when communicating with e Insights API for
 // policies configuration.
 func getHeaders() map[string]string {
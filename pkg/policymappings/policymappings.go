@@ -2,11 +2,19 @@ package policymappings
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/diff"
 	"github.com/fairwindsops/insights-cli/pkg/directory"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/overlay"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
 	"github.com/imroc/req/v3"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
@@ -35,68 +43,146 @@ func AddPolicyMappingsBranch(tree treeprint.Tree, policyMappings []PolicyMapping
 	return nil
 }
 
-// PushPolicyMappings pushes policy-mapping to insights
-func PushPolicyMappings(client *req.Client, pushDir, org string, deleteMissing, dryRun bool) error {
-	logrus.Debugln("Pushing policy-mapping")
+// Plan holds the policy-mapping changes that will be made by ApplyPlan, and a
+// fingerprint of the policy-mappings in Insights at the time the plan was built.
+type Plan struct {
+	Upserts                []PolicyMapping `json:"upserts"`
+	Deletes                []PolicyMapping `json:"deletes"`
+	Diffs                  []diff.Diff     `json:"diffs"`
+	FilePolicyMappingNames []string        `json:"filePolicyMappingNames"`
+	RemoteFingerprint      string          `json:"remoteFingerprint"`
+}
+
+// BuildPlan compares the policy-mappings in pushDir with those in Insights,
+// and returns the changes needed to make Insights match pushDir.
+// Deletions are only planned when deleteMissing is true.
+func BuildPlan(client *req.Client, pushDir, org string, deleteMissing bool) (*Plan, error) {
 	_, err := os.Stat(pushDir)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	existingPolicyMappings, err := FetchPolicyMappings(client, org)
+	if err != nil {
+		return nil, fmt.Errorf("error during API call: %w", err)
+	}
+
+	upserts, deletes, diffs, fileNames, err := comparePolicyMappings(pushDir, existingPolicyMappings)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare and push policy-mapping to Insights: %w", err)
+	}
+	fingerprint, err := utils.Fingerprint(existingPolicyMappings)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint policy-mappings: %w", err)
 	}
+	plan := Plan{Upserts: upserts, Diffs: diffs, FilePolicyMappingNames: fileNames, RemoteFingerprint: fingerprint}
+	if deleteMissing {
+		plan.Deletes = deletes
+	}
+	return &plan, nil
+}
 
+// BuildRestorePlan returns the changes needed to make the policy-mappings in Insights
+// match those given, which were previously retrieved with FetchPolicyMappings. Policy-mappings
+// that are not given are deleted.
+func BuildRestorePlan(client *req.Client, org string, desired []PolicyMapping) (*Plan, error) {
 	existingPolicyMappings, err := FetchPolicyMappings(client, org)
 	if err != nil {
-		return fmt.Errorf("error during API call: %w", err)
+		return nil, fmt.Errorf("error during API call: %w", err)
 	}
+	fingerprint, err := utils.Fingerprint(existingPolicyMappings)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint policy-mappings: %w", err)
+	}
+	upserts, deletes, diffs := getPolicyMappingsDifferences(desired, existingPolicyMappings)
+	return &Plan{Upserts: upserts, Deletes: deletes, Diffs: diffs, RemoteFingerprint: fingerprint}, nil
+}
 
-	upserts, deletes, err := comparePolicyMappings(pushDir, existingPolicyMappings)
+// RemoteFingerprint returns the fingerprint of the policy-mappings in Insights
+// covered by the plan, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	existingPolicyMappings, err := FetchPolicyMappings(client, org)
+	if err != nil {
+		return "", fmt.Errorf("error during API call: %w", err)
+	}
+	fingerprint, err := utils.Fingerprint(existingPolicyMappings)
 	if err != nil {
-		return fmt.Errorf("unable to compare and push policy-mapping to Insights: %w", err)
+		return "", fmt.Errorf("unable to fingerprint policy-mappings: %w", err)
 	}
+	return fingerprint, nil
+}
 
-	for _, policyMapping := range upserts {
+// VerifyPlan returns an error if the policy-mappings in Insights have changed
+// since the plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
+	if err != nil {
+		return err
+	}
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("policy-mappings in Insights have changed since the plan was created")
+	}
+	return nil
+}
+
+// ApplyPlan makes the changes described by the plan in Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	limit := workerpool.Concurrency()
+	if dryRun {
+		limit = 1
+	}
+	err := deletion.Confirm("policy-mappings", lo.Map(plan.Deletes, func(i PolicyMapping, _ int) string { return i.Name }), dryRun)
+	if err != nil {
+		return err
+	}
+	err = workerpool.Run(limit, plan.Upserts, func(policyMapping PolicyMapping) error {
 		logrus.Infof("upsert policy-mapping: %s", policyMapping.Name)
-		if !dryRun {
-			err = upsertPolicyMapping(client, org, policyMapping)
-			if err != nil {
-				return fmt.Errorf("error while upsert policy-mapping %s to Fairwinds Insights: %w", policyMapping.Name, err)
-			}
+		if dryRun {
+			return diff.Print(os.Stdout, diff.ForName(plan.Diffs, policyMapping.Name))
+		}
+		err := upsertPolicyMapping(client, org, policyMapping)
+		if err != nil {
+			return fmt.Errorf("error while upsert policy-mapping %s to Fairwinds Insights: %w", policyMapping.Name, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if deleteMissing {
-		for _, policyMappingForDelete := range deletes {
-			logrus.Infof("Deleting policy-mapping: %s", policyMappingForDelete.Name)
-			if !dryRun {
-				err = deletePolicyMapping(client, org, policyMappingForDelete)
-				if err != nil {
-					return fmt.Errorf("error while deleting policy-mapping %s from insights: %w", policyMappingForDelete.Name, err)
-				}
-			}
+	return workerpool.Run(limit, plan.Deletes, func(policyMappingForDelete PolicyMapping) error {
+		logrus.Infof("Deleting policy-mapping: %s", policyMappingForDelete.Name)
+		if dryRun {
+			return nil
 		}
-	}
-	logrus.Debugln("Done pushing policy-mapping")
-	return nil
+		err := deletePolicyMapping(client, org, policyMappingForDelete)
+		if err != nil {
+			return fmt.Errorf("error while deleting policy-mapping %s from insights: %w", policyMappingForDelete.Name, err)
+		}
+		return nil
+	})
 }
 
 // comparePolicyMappings compares a folder vs the policy-mapping returned by the API.
-func comparePolicyMappings(folder string, existingPolicyMappings []PolicyMapping) (upserts, deletes []PolicyMapping, err error) {
-	files, err := directory.ScanFolder(folder)
+func comparePolicyMappings(folder string, existingPolicyMappings []PolicyMapping) (upserts, deletes []PolicyMapping, diffs []diff.Diff, fileNames []string, err error) {
+	files, err := overlay.ScanFolder(folder, directory.ScanFolder)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error scanning directory: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error scanning directory: %w", err)
 	}
 	filePolicyMappings, err := getPolicyMappingsFromFiles(files)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error reading policy-mapping from files: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error reading policy-mapping from files: %w", err)
 	}
-	upserts, deletes = getPolicyMappingsDifferences(filePolicyMappings, existingPolicyMappings)
-	return upserts, deletes, nil
+	upserts, deletes, diffs = getPolicyMappingsDifferences(filePolicyMappings, existingPolicyMappings)
+	fileNames = lo.Map(filePolicyMappings, func(i PolicyMapping, _ int) string { return i.Name })
+	return upserts, deletes, diffs, fileNames, nil
 }
 
 func getPolicyMappingsFromFiles(files map[string][]string) ([]PolicyMapping, error) {
 	var policyMappings []PolicyMapping
 	for _, policyMappingsFiles := range files {
 		for _, filePath := range policyMappingsFiles {
-			content, err := os.ReadFile(filePath)
+			content, err := overlay.ReadFile(filePath)
 			if err != nil {
 				return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
 			}
@@ -117,15 +203,28 @@ func getPolicyMappingsFromFiles(files map[string][]string) ([]PolicyMapping, err
 	return policyMappings, nil
 }
 
-func getPolicyMappingsDifferences(filePolicyMappings, existingPolicyMappings []PolicyMapping) (upserts, deletes []PolicyMapping) {
+// getPolicyMappingsDifferences returns the policy-mappings to upsert and delete to make
+// existingPolicyMappings match filePolicyMappings. PolicyMappings not selected by the push
+// filter are left alone, and only owned policy-mappings are deleted.
+func getPolicyMappingsDifferences(filePolicyMappings, existingPolicyMappings []PolicyMapping) (upserts, deletes []PolicyMapping, diffs []diff.Diff) {
+	filePolicyMappings = lo.Filter(filePolicyMappings, func(i PolicyMapping, _ int) bool { return filter.Matches(i.Name, i.Labels) })
 	filePolicyMappingsByName := lo.KeyBy(filePolicyMappings, func(i PolicyMapping) string { return i.Name })
 	existingPolicyMappingsByName := lo.KeyBy(existingPolicyMappings, func(i PolicyMapping) string { return i.Name })
 
 	for name, filePolicyMapping := range filePolicyMappingsByName {
 		if existingPolicyMapping, found := existingPolicyMappingsByName[name]; found {
-			if !reflect.DeepEqual(filePolicyMapping, existingPolicyMapping) {
+			compared := existingPolicyMapping
+			// labels are not kept in Insights
+			compared.Labels = filePolicyMapping.Labels
+			if !reflect.DeepEqual(filePolicyMapping, compared) {
 				// only update if the policy-mapping has changed
 				upserts = append(upserts, filePolicyMapping)
+				d, err := diff.YAML("policy-mapping", name, existingPolicyMapping, filePolicyMapping)
+				if err != nil {
+					logrus.Warnf("unable to diff policy-mapping %s: %v", name, err)
+					continue
+				}
+				diffs = append(diffs, d)
 			}
 		} else {
 			upserts = append(upserts, filePolicyMapping)
@@ -133,11 +232,13 @@ func getPolicyMappingsDifferences(filePolicyMappings, existingPolicyMappings []P
 	}
 
 	for name, existingPolicyMapping := range existingPolicyMappingsByName {
-		if _, ok := filePolicyMappingsByName[name]; !ok {
+		// labels are only in local files, so policy-mappings only in Insights are not
+		// deleted while a label selector is set
+		if _, ok := filePolicyMappingsByName[name]; !ok && ownership.Owns(name) && filter.MatchesRemote(existingPolicyMapping.Name) {
 			deletes = append(deletes, existingPolicyMapping)
 		}
 	}
-	return upserts, deletes
+	return upserts, deletes, diffs
 }
 
 func getHeaders() map[string]string {
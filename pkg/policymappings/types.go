@@ -10,6 +10,9 @@ type PolicyMapping struct {
 	Name string            `json:"name,omitempty" yaml:"name,omitempty"`
 	Spec PolicyMappingSpec `json:"spec" yaml:"spec"`
 	Type string            `json:"type,omitempty" yaml:"type,omitempty"`
+	// Labels are used to select resources with push --selector. They are
+	// only kept in local files and never sent to Insights.
+	Labels map[string]string `json:"-" yaml:"labels,omitempty"`
 }
 
 type PolicyMappingSpec struct {
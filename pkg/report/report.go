@@ -0,0 +1,316 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report records the results of validate commands, and writes them
+// as JUnit XML, JSON or SARIF for CI systems.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Formats of reports.
+const (
+	FormatJUnit = "junit"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Formats lists the supported report formats.
+var Formats = []string{FormatJUnit, FormatJSON, FormatSARIF}
+
+// TestCase is the result of validating a policy or rule, in Suite, with one
+// test fixture, in Name.
+type TestCase struct {
+	// Suite is the policy or rule that was validated.
+	Suite string `json:"suite"`
+	// Name is the test fixture, such as an input file.
+	Name string `json:"name"`
+	// File is the file of the policy or rule, if there is one.
+	File string `json:"file,omitempty"`
+	// Failure is why the test case failed, and is empty if it passed.
+	Failure string `json:"failure,omitempty"`
+	// Output is more detail, such as the action items a policy returned.
+	Output string `json:"output,omitempty"`
+}
+
+// Passed returns whether the test case passed.
+func (c TestCase) Passed() bool {
+	return c.Failure == ""
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	cases   []TestCase
+)
+
+// Start starts recording test cases, to write a report in format.
+func Start(format string) error {
+	if !slices.Contains(Formats, format) {
+		return fmt.Errorf("report format %q is not one of %s", format, strings.Join(Formats, ", "))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = true
+	cases = nil
+	return nil
+}
+
+// Record records a test case, if Start was called. Terminal colors are
+// removed from its text.
+func Record(c TestCase) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return
+	}
+	c.Failure = stripColors(c.Failure)
+	c.Output = stripColors(c.Output)
+	cases = append(cases, c)
+}
+
+// Cases returns the recorded test cases.
+func Cases() []TestCase {
+	mu.Lock()
+	defer mu.Unlock()
+	return slices.Clone(cases)
+}
+
+// WriteFile writes the recorded test cases to fileName in format, for the
+// named tool, such as "insights-cli validate opa".
+func WriteFile(fileName, format, tool string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	err = Write(f, format, tool, Cases())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Write writes testCases to w in format, for the named tool.
+func Write(w io.Writer, format, tool string, testCases []TestCase) error {
+	switch format {
+	case FormatJUnit:
+		return writeJUnit(w, tool, testCases)
+	case FormatJSON:
+		return writeJSON(w, tool, testCases)
+	case FormatSARIF:
+		return writeSARIF(w, tool, testCases)
+	}
+	return fmt.Errorf("report format %q is not one of %s", format, strings.Join(Formats, ", "))
+}
+
+var colorRE = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripColors(s string) string {
+	return colorRE.ReplaceAllString(s, "")
+}
+
+// suites groups testCases by suite, in the order suites were first seen.
+func suites(testCases []TestCase) ([]string, map[string][]TestCase) {
+	var names []string
+	bySuite := map[string][]TestCase{}
+	for _, c := range testCases {
+		if _, ok := bySuite[c.Suite]; !ok {
+			names = append(names, c.Suite)
+		}
+		bySuite[c.Suite] = append(bySuite[c.Suite], c)
+	}
+	return names, bySuite
+}
+
+func failures(testCases []TestCase) int {
+	n := 0
+	for _, c := range testCases {
+		if !c.Passed() {
+			n++
+		}
+	}
+	return n
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, tool string, testCases []TestCase) error {
+	report := junitTestSuites{Name: tool, Tests: len(testCases), Failures: failures(testCases)}
+	names, bySuite := suites(testCases)
+	for _, name := range names {
+		suite := junitTestSuite{Name: name, Tests: len(bySuite[name]), Failures: failures(bySuite[name])}
+		for _, c := range bySuite[name] {
+			tc := junitTestCase{Name: c.Name, Classname: c.Suite, File: c.File, SystemOut: c.Output}
+			if !c.Passed() {
+				message, _, _ := strings.Cut(c.Failure, "\n")
+				tc.Failure = &junitFailure{Message: message, Text: c.Failure}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+type jsonReport struct {
+	Tool     string     `json:"tool"`
+	Tests    int        `json:"tests"`
+	Failures int        `json:"failures"`
+	Cases    []jsonCase `json:"testCases"`
+}
+
+type jsonCase struct {
+	TestCase
+	Passed bool `json:"passed"`
+}
+
+func writeJSON(w io.Writer, tool string, testCases []TestCase) error {
+	report := jsonReport{Tool: tool, Tests: len(testCases), Failures: failures(testCases), Cases: []jsonCase{}}
+	for _, c := range testCases {
+		report.Cases = append(report.Cases, jsonCase{TestCase: c, Passed: c.Passed()})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// writeSARIF writes a result for each failed test case, with a rule for
+// each suite.
+func writeSARIF(w io.Writer, tool string, testCases []TestCase) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           tool,
+			InformationURI: "https://github.com/FairwindsOps/insights-cli",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	names, bySuite := suites(testCases)
+	for _, name := range names {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: name})
+		for _, c := range bySuite[name] {
+			if c.Passed() {
+				continue
+			}
+			result := sarifResult{RuleID: c.Suite, Level: "error", Message: sarifMessage{Text: c.Failure}}
+			if c.Name != "" {
+				result.Message.Text = c.Name + ": " + c.Failure
+			}
+			if c.File != "" {
+				result.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(c.File)}}}}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCases = []TestCase{
+	{Suite: "policy.rego", Name: "policy.success.yaml", File: "opa/policy.rego", Output: "✔ Action Item"},
+	{Suite: "policy.rego", Name: "policy.failure.yaml", File: "opa/policy.rego", Failure: "1 action item is invalid\nTitle is required"},
+	{Suite: "other.rego", Name: "other.yaml", File: "opa/other.rego"},
+}
+
+func TestRecord(t *testing.T) {
+	Record(testCases[0])
+	assert.Empty(t, Cases(), "cases are only recorded after Start")
+
+	assert.Error(t, Start("html"))
+	assert.NoError(t, Start(FormatJSON))
+	Record(TestCase{Suite: "rule.js", Failure: "\x1b[0;31mTest failed\x1b[0m"})
+	assert.Equal(t, []TestCase{{Suite: "rule.js", Failure: "Test failed"}}, Cases())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Write(&b, FormatJUnit, "insights-cli validate opa", testCases))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="insights-cli validate opa" tests="3" failures="1">
+  <testsuite name="policy.rego" tests="2" failures="1">
+    <testcase name="policy.success.yaml" classname="policy.rego" file="opa/policy.rego">
+      <system-out>✔ Action Item</system-out>
+    </testcase>
+    <testcase name="policy.failure.yaml" classname="policy.rego" file="opa/policy.rego">
+      <failure message="1 action item is invalid">1 action item is invalid&#xA;Title is required</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="other.rego" tests="1" failures="0">
+    <testcase name="other.yaml" classname="other.rego" file="opa/other.rego"></testcase>
+  </testsuite>
+</testsuites>
+`, b.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Write(&b, FormatJSON, "insights-cli validate opa", testCases))
+	var got jsonReport
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Equal(t, 3, got.Tests)
+	assert.Equal(t, 1, got.Failures)
+	assert.Len(t, got.Cases, 3)
+	assert.True(t, got.Cases[0].Passed)
+	assert.False(t, got.Cases[1].Passed)
+	assert.Equal(t, "policy.failure.yaml", got.Cases[1].Name)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Write(&b, FormatSARIF, "insights-cli validate opa", testCases))
+	var got sarifLog
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Equal(t, "2.1.0", got.Version)
+	assert.Len(t, got.Runs, 1)
+	assert.Equal(t, []sarifRule{{ID: "policy.rego"}, {ID: "other.rego"}}, got.Runs[0].Tool.Driver.Rules)
+	assert.Equal(t, []sarifResult{{
+		RuleID:    "policy.rego",
+		Level:     "error",
+		Message:   sarifMessage{Text: "policy.failure.yaml: 1 action item is invalid\nTitle is required"},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "opa/policy.rego"}}}},
+	}}, got.Runs[0].Results)
+}
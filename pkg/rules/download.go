@@ -0,0 +1,81 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/imroc/req/v3"
+)
+
+// ruleFile is the content of the YAML file of a downloaded rule, whose
+// action is saved to a separate JavaScript file.
+type ruleFile struct {
+	Name        string `yaml:"name"`
+	Cluster     string `yaml:"cluster,omitempty"`
+	Context     string `yaml:"context,omitempty"`
+	ReportType  string `yaml:"reportType,omitempty"`
+	Repository  string `yaml:"repository,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+var fileNameRegex = regexp.MustCompile("[^A-Za-z0-9]+")
+
+// DownloadRules writes each automation rule in Insights to saveDir, as a YAML
+// file of its settings and a JavaScript file of its action, which BuildPlan
+// reads back. It returns the number of rules written.
+func DownloadRules(client *req.Client, org, saveDir string) (int, error) {
+	rules, err := FetchRules(client, org)
+	if err != nil {
+		return 0, err
+	}
+	written := map[string]string{}
+	for _, rule := range rules {
+		baseName := fileNameRegex.ReplaceAllString(rule.Name, "-")
+		if other, found := written[baseName]; found {
+			return 0, fmt.Errorf("rules %q and %q would be written to the same file %s.yaml", other, rule.Name, baseName)
+		}
+		written[baseName] = rule.Name
+		err := writeRule(filepath.Join(saveDir, baseName), rule)
+		if err != nil {
+			return 0, fmt.Errorf("error writing rule %s: %w", rule.Name, err)
+		}
+	}
+	return len(rules), nil
+}
+
+func writeRule(basePath string, rule Rule) error {
+	b, err := yaml.Marshal(ruleFile{
+		Name:        rule.Name,
+		Cluster:     rule.Cluster,
+		Context:     rule.Context,
+		ReportType:  rule.ReportType,
+		Repository:  rule.Repository,
+		Description: rule.Description,
+	})
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(basePath+".yaml", b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(basePath+actionFileExtension, []byte(rule.Action), 0644)
+}
@@ -0,0 +1,243 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// offlineTimeout limits how long a rule script may run offline.
+var offlineTimeout = 10 * time.Second
+
+// integrationEventTypes maps the Insights integration helpers available to
+// rule scripts to the type of event recorded when they are called. Offline,
+// the helpers only record their calls, and return a successful response
+// from offlineIntegrationResponse.
+var integrationEventTypes = map[string]string{
+	"sendSlackNotification":   "slack_notification",
+	"createJiraTicket":        "jira_ticket",
+	"createAzureTicket":       "azure_ticket",
+	"createGitHubTicket":      "github_ticket",
+	"createPagerDutyIncident": "pagerduty_incident",
+	"sendHTTPRequest":         "http_request",
+}
+
+// offlineIntegrationResponse returns the response of an integration helper
+// called offline: a status of 200 and an empty body, so that rules reading
+// the response of a helper can run.
+func offlineIntegrationResponse() map[string]any {
+	return map[string]any{"status": 200, "body": ""}
+}
+
+// integrationCall is a call to an integration helper, recorded when a rule
+// runs offline.
+type integrationCall struct {
+	Function string `json:"function" yaml:"function"`
+	Args     []any  `json:"args" yaml:"args"`
+}
+
+// runVerifyRuleOffline runs rule against its action item with an embedded
+// JavaScript runtime, instead of the Insights API. It returns the action
+// item and events in the form returned by Insights, and the calls made to
+// integration helpers, none of which are sent.
+func runVerifyRuleOffline(rule verifyRule, dryRun bool) (*verifyWithEvents, []integrationCall, error) {
+	before, err := actionItemFields(rule.ActionItem)
+	if err != nil {
+		return nil, nil, err
+	}
+	var events []ruleExecutionEvent
+	var calls []integrationCall
+	vm := goja.New()
+	for name, eventType := range integrationEventTypes {
+		err = vm.Set(name, func(call goja.FunctionCall) goja.Value {
+			args := make([]any, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				args[i] = arg.Export()
+			}
+			calls = append(calls, integrationCall{Function: name, Args: args})
+			events = append(events, ruleExecutionEvent{
+				CreatedAt: time.Now().UTC(),
+				Details:   fmt.Sprintf("%s was called offline, and not sent", name),
+				DryRun:    true,
+				Level:     "info",
+				Type:      eventType,
+			})
+			return vm.ToValue(offlineIntegrationResponse())
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	console := vm.NewObject()
+	err = console.Set("log", func(call goja.FunctionCall) goja.Value {
+		args := make([]string, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			args[i] = arg.String()
+		}
+		events = append(events, ruleExecutionEvent{
+			CreatedAt: time.Now().UTC(),
+			Details:   strings.Join(args, " "),
+			DryRun:    dryRun,
+			Level:     "info",
+			Type:      "log",
+		})
+		return goja.Undefined()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = vm.Set("console", console)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := json.Marshal(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed, err := vm.RunString("JSON.parse(" + jsString(string(b)) + ")")
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to convert action item for the rule: %w", err)
+	}
+	err = vm.Set("ActionItem", parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timer := time.AfterFunc(offlineTimeout, func() {
+		vm.Interrupt(fmt.Sprintf("rule did not finish within %s", offlineTimeout))
+	})
+	_, err = vm.RunString(rule.Script)
+	timer.Stop()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error running rule: %w", err)
+	}
+
+	result, err := vm.RunString("JSON.stringify(ActionItem)")
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the action item after running the rule: %w", err)
+	}
+	var after map[string]any
+	err = json.Unmarshal([]byte(result.String()), &after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the action item after running the rule: %w", err)
+	}
+	ai, err := actionItemFromFields(after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid action item after running the rule: %w", err)
+	}
+	normalized, err := actionItemFields(ai)
+	if err != nil {
+		return nil, nil, err
+	}
+	if changes := changelogOf(before, normalized); len(changes) > 0 {
+		events = append([]ruleExecutionEvent{{
+			Changelog: changes,
+			CreatedAt: time.Now().UTC(),
+			Details:   "action item edited",
+			DryRun:    dryRun,
+			Level:     "info",
+			Type:      "edit_action_item",
+		}}, events...)
+	}
+	return &verifyWithEvents{ActionItem: ai, Events: events}, calls, nil
+}
+
+// jsString returns s as a JavaScript string literal.
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// actionItemFields returns the fields of ai keyed by the names rule scripts
+// use, which are those of the actionItem struct. Fields that are not set
+// are null.
+func actionItemFields(ai actionItem) (map[string]any, error) {
+	b, err := json.Marshal(ai)
+	if err != nil {
+		return nil, err
+	}
+	var byJSONName map[string]any
+	err = json.Unmarshal(b, &byJSONName)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	for name, jsonName := range actionItemFieldNames() {
+		fields[name] = byJSONName[jsonName]
+	}
+	return fields, nil
+}
+
+// actionItemFromFields is the reverse of actionItemFields.
+func actionItemFromFields(fields map[string]any) (actionItem, error) {
+	byJSONName := map[string]any{}
+	for name, jsonName := range actionItemFieldNames() {
+		if v, ok := fields[name]; ok && v != nil {
+			byJSONName[jsonName] = v
+		}
+	}
+	var ai actionItem
+	b, err := json.Marshal(byJSONName)
+	if err != nil {
+		return ai, err
+	}
+	err = json.Unmarshal(b, &ai)
+	return ai, err
+}
+
+// actionItemFieldNames maps the names of the fields of actionItem to their
+// JSON names.
+func actionItemFieldNames() map[string]string {
+	names := map[string]string{}
+	t := reflect.TypeFor[actionItem]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		names[f.Name] = jsonName
+	}
+	return names
+}
+
+// changelogOf returns the changes from the fields before to those after, in
+// the form Insights reports them.
+func changelogOf(before, after map[string]any) []changelog {
+	var changes []changelog
+	names := make([]string, 0, len(before))
+	for name := range before {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		from, to := before[name], after[name]
+		if reflect.DeepEqual(from, to) {
+			continue
+		}
+		changeType := "update"
+		if from == nil {
+			changeType = "create"
+		} else if to == nil {
+			changeType = "delete"
+		}
+		changes = append(changes, changelog{From: from, Path: []string{name}, To: to, Type: changeType})
+	}
+	return changes
+}
@@ -18,6 +18,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
@@ -25,7 +27,14 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/xlab/treeprint"
 
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/diff"
 	"github.com/fairwindsops/insights-cli/pkg/directory"
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
+	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/variables"
+	"github.com/fairwindsops/insights-cli/pkg/workerpool"
 	"github.com/imroc/req/v3"
 )
 
@@ -43,6 +52,9 @@ type Rule struct {
 	ReportType  string `json:"reportType" yaml:"reportType"`
 	Repository  string
 	Action      string
+	// Labels are used to select rules with push --selector. They are only
+	// kept in local files and never sent to Insights.
+	Labels map[string]string `json:"-" yaml:"labels,omitempty"`
 }
 
 // CompareResults holds the rules for inserting, updating, and deleting
@@ -50,10 +62,11 @@ type CompareResults struct {
 	RuleInsert []Rule
 	RuleUpdate []Rule
 	RuleDelete []Rule
+	Diffs      []diff.Diff
 }
 
-// getRules queries Fairwinds Insights to retrieve all of the Rules for an organization
-func getRules(client *req.Client, org string) ([]Rule, error) {
+// FetchRules queries Fairwinds Insights to retrieve all of the Rules for an organization
+func FetchRules(client *req.Client, org string) ([]Rule, error) {
 	url := fmt.Sprintf(rulesURLFormat, org)
 	logrus.Debugf("Rules URL: %s", url)
 	resp, err := client.R().SetHeaders(getHeaders()).Get(url)
@@ -63,8 +76,7 @@ func getRules(client *req.Client, org string) ([]Rule, error) {
 	}
 	var rules []Rule
 	if !resp.IsSuccessState() {
-		logrus.Errorf("getRules: invalid response code: %s %v", string(resp.Bytes()), resp.StatusCode)
-		return nil, errors.New("getRules: invalid response code")
+		return nil, fmt.Errorf("FetchRules: invalid response code %d: %s", resp.StatusCode, string(resp.Bytes()))
 	}
 	err = resp.Unmarshal(&rules)
 	if err != nil {
@@ -121,7 +133,7 @@ func deleteRule(client *req.Client, org string, rule Rule) error {
 
 // AddRulesBranch builds a tree for rules
 func AddRulesBranch(client *req.Client, org string, tree treeprint.Tree) error {
-	rules, err := getRules(client, org)
+	rules, err := FetchRules(client, org)
 	if err != nil {
 		logrus.Errorf("Unable to get rules from insights: %v", err)
 		return err
@@ -149,11 +161,20 @@ func AddRulesBranch(client *req.Client, org string, tree treeprint.Tree) error {
 	return nil
 }
 
+// actionFileExtension is the extension of a file holding the action of the
+// rule in the YAML file with the same base name, as an alternative to the
+// inline action field.
+const actionFileExtension = ".js"
+
 func getRulesFromFiles(files map[string][]string) ([]Rule, error) {
 	var rules []Rule
 	for _, ruleFiles := range files {
 		for _, filePath := range ruleFiles {
-			fileContents, err := os.ReadFile(filePath)
+			if strings.ToLower(filepath.Ext(filePath)) == actionFileExtension {
+				// read alongside the YAML file of its rule
+				continue
+			}
+			fileContents, err := variables.ReadFile(filePath)
 			if err != nil {
 				logrus.Error(err, "Error reading file", filePath)
 				return nil, err
@@ -167,6 +188,16 @@ func getRulesFromFiles(files map[string][]string) ([]Rule, error) {
 			if rule.Name == "" {
 				return nil, fmt.Errorf("Rule name is empty in file: %s", filePath)
 			}
+			actionFilePath := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + actionFileExtension
+			action, err := variables.ReadFile(actionFilePath)
+			if err == nil {
+				if rule.Action != "" {
+					return nil, fmt.Errorf("Rule action is set in both %s and %s", filePath, actionFilePath)
+				}
+				rule.Action = string(action)
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("error reading rule action file %s: %w", actionFilePath, err)
+			}
 			if rule.Action == "" {
 				return nil, fmt.Errorf("Rule action is empty in file: %s", filePath)
 			}
@@ -199,6 +230,7 @@ func ruleNeedsUpdate(fileRule, existingRule Rule) bool {
 }
 
 func getRuleDifferences(fileRules, existingRules []Rule) CompareResults {
+	fileRules = lo.Filter(fileRules, func(i Rule, _ int) bool { return filter.Matches(i.Name, i.Labels) })
 	mappedFileRules := lo.KeyBy(fileRules, func(i Rule) string { return i.Name })
 	mappedExistingRules := lo.KeyBy(existingRules, func(i Rule) string { return i.Name })
 	var results CompareResults
@@ -207,6 +239,7 @@ func getRuleDifferences(fileRules, existingRules []Rule) CompareResults {
 			if ruleNeedsUpdate(fileRule, existingRule) {
 				fileRule.ID = existingRule.ID
 				results.RuleUpdate = append(results.RuleUpdate, fileRule)
+				results.Diffs = append(results.Diffs, ruleDiffs(fileRule, existingRule)...)
 			}
 		} else {
 			results.RuleInsert = append(results.RuleInsert, fileRule)
@@ -214,86 +247,173 @@ func getRuleDifferences(fileRules, existingRules []Rule) CompareResults {
 	}
 
 	for ruleName, existingRule := range mappedExistingRules {
-		if _, ok := mappedFileRules[ruleName]; !ok {
+		// labels are only in local files, so rules only in Insights are not
+		// deleted while a label selector is set
+		if _, ok := mappedFileRules[ruleName]; !ok && ownership.Owns(ruleName) && filter.MatchesRemote(existingRule.Name) {
 			results.RuleDelete = append(results.RuleDelete, existingRule)
 		}
 	}
 	return results
 }
 
-// compareRules compares a folder vs the rules returned by the API.
-func compareRules(client *req.Client, folder, org string) (CompareResults, error) {
-	var results CompareResults
-	files, err := directory.ScanFolder(folder)
+// ruleDiffs returns the differences between the action and the other
+// settings of a local rule and its counterpart in Insights.
+func ruleDiffs(fileRule, existingRule Rule) []diff.Diff {
+	diffs := []diff.Diff{{Resource: "automation rule", Name: fileRule.Name, Format: "js", Remote: existingRule.Action, Local: fileRule.Action}}
+	fileRule.Action, existingRule.Action = "", ""
+	if ruleNeedsUpdate(fileRule, existingRule) {
+		settingsDiff, err := diff.YAML("automation rule", fileRule.Name, existingRule, fileRule)
+		if err != nil {
+			logrus.Warnf("unable to diff automation rule %s: %v", fileRule.Name, err)
+			return diffs
+		}
+		diffs = append(diffs, settingsDiff)
+	}
+	return diffs
+}
+
+// Plan holds the automation rule changes that will be made by ApplyPlan, and
+// a fingerprint of the rules in Insights at the time the plan was built.
+type Plan struct {
+	CompareResults
+	FileRuleNames     []string `json:"fileRuleNames"`
+	RemoteFingerprint string   `json:"remoteFingerprint"`
+}
+
+// BuildPlan compares the automation rules in pushDir with those in Insights,
+// and returns the changes needed to make Insights match pushDir.
+// Deletions are only planned when deleteMissing is true.
+func BuildPlan(client *req.Client, pushDir, org string, deleteMissing bool) (*Plan, error) {
+	_, err := os.Stat(pushDir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := directory.ScanFolder(pushDir)
 	if err != nil {
 		logrus.Error("Error scanning directory")
-		return results, err
+		return nil, err
 	}
-
 	fileRules, err := getRulesFromFiles(files)
 	if err != nil {
 		logrus.Error("Error reading checks from files")
-		return results, err
+		return nil, err
 	}
-	existingRules, err := getRules(client, org)
+	existingRules, err := FetchRules(client, org)
 	if err != nil {
 		logrus.Error("Error during API call")
-		return results, err
+		return nil, err
+	}
+	fingerprint, err := utils.Fingerprint(existingRules)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint rules: %w", err)
+	}
+	plan := Plan{
+		CompareResults:    getRuleDifferences(fileRules, existingRules),
+		FileRuleNames:     lo.Map(fileRules, func(r Rule, _ int) string { return r.Name }),
+		RemoteFingerprint: fingerprint,
 	}
+	if !deleteMissing {
+		plan.RuleDelete = nil
+	}
+	return &plan, nil
+}
 
-	results = getRuleDifferences(fileRules, existingRules)
-	return results, nil
+// BuildRestorePlan returns the changes needed to make the automation rules in
+// Insights match those given, which were previously retrieved with
+// FetchRules. Rules that are not given are deleted.
+func BuildRestorePlan(client *req.Client, org string, desired []Rule) (*Plan, error) {
+	existingRules, err := FetchRules(client, org)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := utils.Fingerprint(existingRules)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint rules: %w", err)
+	}
+	return &Plan{CompareResults: getRuleDifferences(desired, existingRules), RemoteFingerprint: fingerprint}, nil
 }
 
-// PushRules pushes automation rules to insights
-func PushRules(client *req.Client, pushDir, org string, deleteMissing, dryRun bool) error {
-	logrus.Debugln("Pushing automation rules")
-	_, err := os.Stat(pushDir)
+// RemoteFingerprint returns the fingerprint of the automation rules in Insights
+// covered by the plan, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	existingRules, err := FetchRules(client, org)
 	if err != nil {
-		return err
+		return "", err
 	}
-	results, err := compareRules(client, pushDir, org)
+	fingerprint, err := utils.Fingerprint(existingRules)
+	if err != nil {
+		return "", fmt.Errorf("unable to fingerprint rules: %w", err)
+	}
+	return fingerprint, nil
+}
+
+// VerifyPlan returns an error if the automation rules in Insights have
+// changed since the plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
 	if err != nil {
-		logrus.Errorf("unable to compare and push rules to Insights: %v", err)
 		return err
 	}
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("automation rules in Insights have changed since the plan was created")
+	}
+	return nil
+}
 
-	for _, ruleForInsert := range results.RuleInsert {
+// ApplyPlan makes the changes described by the plan in Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	limit := workerpool.Concurrency()
+	if dryRun {
+		limit = 1
+	}
+	err := deletion.Confirm("automation rules", lo.Map(plan.RuleDelete, func(r Rule, _ int) string { return r.Name }), dryRun)
+	if err != nil {
+		return err
+	}
+	err = workerpool.Run(limit, plan.RuleInsert, func(ruleForInsert Rule) error {
 		logrus.Infof("Adding automation rule: %s", ruleForInsert.Name)
-		if !dryRun {
-			err = insertRule(client, org, ruleForInsert)
-			if err != nil {
-				logrus.Errorf("Error while adding rule %s to insights: %v", ruleForInsert.Name, err)
-				return err
-			}
+		if dryRun {
+			return nil
+		}
+		err := insertRule(client, org, ruleForInsert)
+		if err != nil {
+			logrus.Errorf("Error while adding rule %s to insights: %v", ruleForInsert.Name, err)
+			return fmt.Errorf("error adding rule %s: %w", ruleForInsert.Name, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	for _, ruleForUpdate := range results.RuleUpdate {
+	err = workerpool.Run(limit, plan.RuleUpdate, func(ruleForUpdate Rule) error {
 		logrus.Infof("Updating automation rule: %s", ruleForUpdate.Name)
-		if !dryRun {
-			err = updateRule(client, org, ruleForUpdate)
-			if err != nil {
-				logrus.Errorf("Error while updating rule %s to insights: %v", ruleForUpdate.Name, err)
-				return err
-			}
+		if dryRun {
+			return diff.Print(os.Stdout, diff.ForName(plan.Diffs, ruleForUpdate.Name))
+		}
+		err := updateRule(client, org, ruleForUpdate)
+		if err != nil {
+			logrus.Errorf("Error while updating rule %s to insights: %v", ruleForUpdate.Name, err)
+			return fmt.Errorf("error updating rule %s: %w", ruleForUpdate.Name, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if deleteMissing {
-		for _, ruleForDelete := range results.RuleDelete {
-			logrus.Infof("Deleting automation rule: %s", ruleForDelete.Name)
-			if !dryRun {
-				err = deleteRule(client, org, ruleForDelete)
-				if err != nil {
-					logrus.Errorf("Error while deleting rule %s from insights: %v", ruleForDelete.Name, err)
-					return err
-				}
-			}
+	return workerpool.Run(limit, plan.RuleDelete, func(ruleForDelete Rule) error {
+		logrus.Infof("Deleting automation rule: %s", ruleForDelete.Name)
+		if dryRun {
+			return nil
 		}
-	}
-	logrus.Debugln("Done pushing automation rules")
-	return nil
+		err := deleteRule(client, org, ruleForDelete)
+		if err != nil {
+			logrus.Errorf("Error while deleting rule %s from insights: %v", ruleForDelete.Name, err)
+			return fmt.Errorf("error deleting rule %s: %w", ruleForDelete.Name, err)
+		}
+		return nil
+	})
 }
 
 func getHeaders() map[string]string {
@@ -15,9 +15,19 @@
 package rules
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/fairwindsops/insights-cli/pkg/filter"
+	"github.com/fairwindsops/insights-cli/pkg/ownership"
 )
 
 // FWI-3100 - the rule variable was not being reset - so previous values were being re-used
@@ -31,3 +41,180 @@ func TestGetRulesFromFiles(t *testing.T) {
 	assert.Equal(t, "us-east-1", rules[0].Cluster)
 	assert.Equal(t, "", rules[1].Cluster)
 }
+
+func TestGetRulesFromFilesWithActionFile(t *testing.T) {
+	folderRules := map[string][]string{
+		"split": {"testdata/split/rule3.js", "testdata/split/rule3.yaml"},
+	}
+	rules, err := getRulesFromFiles(folderRules)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	assert.Equal(t, "rule-3", rules[0].Name)
+	assert.Contains(t, rules[0].Action, "web-team@acme-co.com")
+
+	// an inline action and an action file conflict
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "rule.yaml"), []byte("name: rule\naction: x\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "rule.js"), []byte("y"), 0644))
+	_, err = getRulesFromFiles(map[string][]string{"dir": {filepath.Join(dir, "rule.yaml"), filepath.Join(dir, "rule.js")}})
+	assert.Error(t, err)
+}
+
+func TestWriteRuleRoundTrip(t *testing.T) {
+	rule := Rule{Name: "rule 4", Cluster: "prod", ReportType: "trivy", Action: "ActionItem.Notes = 'x';\n", Labels: map[string]string{"team": "web"}}
+	basePath := filepath.Join(t.TempDir(), "rule-4")
+	assert.NoError(t, writeRule(basePath, rule))
+	rules, err := getRulesFromFiles(map[string][]string{"dir": {basePath + ".js", basePath + ".yaml"}})
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	assert.False(t, ruleNeedsUpdate(rules[0], rule))
+}
+
+func TestGetRuleDifferencesOwnership(t *testing.T) {
+	existingRules := []Rule{
+		{ID: 1, Name: "owned", Action: "a"},
+		{ID: 2, Name: "other", Action: "a"},
+		{ID: 3, Name: "kept", Action: "a"},
+	}
+	fileRules := []Rule{{Name: "kept", Action: "a"}}
+
+	results := getRuleDifferences(fileRules, existingRules)
+	assert.Len(t, results.RuleDelete, 2, "without an owner, every missing rule is deleted")
+
+	ownership.SetOwner("acme/policies")
+	defer ownership.SetOwner("")
+	defer ownership.SetOwned(nil)
+	ownership.SetOwned(nil)
+	results = getRuleDifferences(fileRules, existingRules)
+	assert.Empty(t, results.RuleDelete, "rules not recorded as pushed with the owner are not deleted")
+
+	ownership.SetOwned([]string{"owned", "kept"})
+	results = getRuleDifferences(fileRules, existingRules)
+	assert.Len(t, results.RuleDelete, 1)
+	assert.Equal(t, "owned", results.RuleDelete[0].Name)
+	assert.Empty(t, results.RuleUpdate)
+}
+
+func TestBuildPlanFileRuleNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`[
+			{"ID": 1, "Cluster": "", "Name": "kept", "Description": "", "Context": "Agent", "ReportType": "", "Repository": "", "Action": "a"},
+			{"ID": 2, "Cluster": "", "Name": "unowned", "Description": "", "Context": "Agent", "ReportType": "", "Repository": "", "Action": "a"}
+		]`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	client := req.C().SetBaseURL(server.URL)
+	pushDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(pushDir, "kept.yaml"), []byte("name: kept\ncontext: Agent\naction: a\n"), 0644))
+	ownership.SetOwner("acme/policies")
+	defer ownership.SetOwner("")
+	ownership.SetOwned(nil)
+
+	plan, err := BuildPlan(client, pushDir, "acme", true)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.RuleUpdate)
+	assert.Empty(t, plan.RuleDelete, "rules not recorded as pushed with the owner are not deleted")
+	assert.Equal(t, []string{"kept"}, plan.FileRuleNames)
+}
+
+func TestGetRuleDifferencesFilter(t *testing.T) {
+	f, err := filter.New(nil, []string{"legacy-*"}, "team=web")
+	assert.NoError(t, err)
+	filter.Set(f)
+	defer filter.Set(filter.Filter{})
+	web := map[string]string{"team": "web"}
+	existingRules := []Rule{
+		{ID: 1, Name: "web-old"},
+		{ID: 2, Name: "api-kept", Action: "a"},
+		{ID: 3, Name: "legacy-old"},
+	}
+	fileRules := []Rule{
+		{Name: "web-new", Labels: web},
+		{Name: "api-kept", Action: "b", Labels: map[string]string{"team": "api"}},
+		{Name: "legacy-new", Labels: web},
+	}
+
+	results := getRuleDifferences(fileRules, existingRules)
+	assert.Len(t, results.RuleInsert, 1)
+	assert.Equal(t, "web-new", results.RuleInsert[0].Name)
+	assert.Empty(t, results.RuleUpdate, "rules not selected by the selector are not updated")
+	assert.Empty(t, results.RuleDelete, "labels of rules only in Insights are unknown, so none are deleted with a selector")
+
+	f, err = filter.New(nil, []string{"legacy-*"}, "")
+	assert.NoError(t, err)
+	filter.Set(f)
+	results = getRuleDifferences(fileRules, existingRules)
+	assert.Equal(t, []string{"web-old"}, lo.Map(results.RuleDelete, func(r Rule, _ int) string { return r.Name }))
+}
+
+func TestRunVerifyRuleOffline(t *testing.T) {
+	title := "Image has vulnerabilities"
+	reportType := "trivy"
+	severity := float32(0.8)
+	rule := verifyRule{
+		ActionItem: actionItem{Title: &title, ReportType: &reportType, Severity: &severity, ResourceLabels: map[string]string{"app": "web"}},
+		Script: `
+ActionItem.Severity = 0.9;
+ActionItem.ResourceLabels.team = "payments";
+ActionItem.Notes = "raised";
+createJiraTicket(ActionItem, "SEC", "Bug", ["trivy"]);`,
+	}
+	r, calls, err := runVerifyRuleOffline(rule, false)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(0.9), *r.ActionItem.Severity)
+	assert.Equal(t, "raised", *r.ActionItem.Notes)
+	assert.Equal(t, map[string]string{"app": "web", "team": "payments"}, r.ActionItem.ResourceLabels)
+
+	assert.Len(t, r.Events, 2)
+	assert.Equal(t, "edit_action_item", r.Events[0].Type)
+	assert.Equal(t, []changelog{
+		{Type: "create", Path: []string{"Notes"}, From: nil, To: "raised"},
+		{Type: "update", Path: []string{"ResourceLabels"}, From: map[string]any{"app": "web"}, To: map[string]any{"app": "web", "team": "payments"}},
+		{Type: "update", Path: []string{"Severity"}, From: 0.8, To: 0.9},
+	}, r.Events[0].Changelog)
+	assert.Equal(t, "jira_ticket", r.Events[1].Type)
+	assert.True(t, r.Events[1].DryRun)
+
+	assert.Len(t, calls, 1)
+	assert.Equal(t, "createJiraTicket", calls[0].Function)
+	assert.Equal(t, "SEC", calls[0].Args[1])
+
+	offlineTimeout = 10 * time.Millisecond
+	rule.Script = "while (true) {}"
+	_, _, err = runVerifyRuleOffline(rule, false)
+	assert.ErrorContains(t, err, "did not finish")
+}
+
+func TestRunVerifyRuleOfflineIntegrationResponse(t *testing.T) {
+	title := "Image has vulnerabilities"
+	rule := verifyRule{
+		ActionItem: actionItem{Title: &title},
+		Script: `
+var response = sendHTTPRequest("POST", "https://example.com/hook", {}, "body");
+if (response.status !== 200 || response.body !== "") {
+  throw new Error("unexpected response");
+}
+ActionItem.Notes = "sent " + response.status;`,
+	}
+	r, calls, err := runVerifyRuleOffline(rule, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "sent 200", *r.ActionItem.Notes)
+	assert.Len(t, calls, 1)
+	assert.Equal(t, "sendHTTPRequest", calls[0].Function)
+}
+
+func TestFindRuleCases(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"severity.js", "severity.prod.input.yaml", "severity.prod.expected.yaml", "severity.dev.input.yaml", "severity.v2.js", "severity.v2.prod.input.yaml", "other.prod.input.yaml"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	caseNames, err := findRuleCases(filepath.Join(dir, "severity.js"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, caseNames)
+
+	caseNames, err = findRuleCases(filepath.Join(dir, "severity.v2.js"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, caseNames)
+}
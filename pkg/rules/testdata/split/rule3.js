@@ -0,0 +1,3 @@
+if (ActionItem.ResourceNamespace === 'web') {
+  ActionItem.AssigneeEmail = 'web-team@acme-co.com';
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 	"github.com/imroc/req/v3"
 	"github.com/sirupsen/logrus"
 	"sigs.k8s.io/yaml" // this lib correctly handles null slices
+
+	"github.com/fairwindsops/insights-cli/pkg/report"
 )
 
 const rulesURLVerify = "/v0/organizations/%s/rules/verify-with-events"
@@ -166,36 +170,52 @@ func runVerifyRule(client *req.Client, org string, rule verifyRule, dryRun bool)
 	return &verify, nil
 }
 
-func ValidateRule(client *req.Client, org, automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext string, dryRun bool) error {
+// ValidateRule runs the automation rule against the action item, with the
+// Insights API or offline, and compares the result with the expected action
+// item, if given.
+// The result is recorded as a test case of the rule for reports.
+func ValidateRule(client *req.Client, org, automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext string, dryRun, offline bool) error {
+	diff, err := validateRule(client, org, automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext, dryRun, offline)
+	testCase := report.TestCase{Suite: automationRuleFilePath, Name: actionItemFilePath, File: automationRuleFilePath}
+	if err != nil {
+		testCase.Failure = strings.TrimSpace(err.Error() + "\n\n" + diff)
+	}
+	report.Record(testCase)
+	return err
+}
+
+// validateRule runs the automation rule against the action item, returning
+// the difference from the expected action item, if any.
+func validateRule(client *req.Client, org, automationRuleFilePath, actionItemFilePath, expectedActionItemFilePath, insightsContext string, dryRun, offline bool) (string, error) {
 	aiInput, err := os.Open(actionItemFilePath)
 	if err != nil {
-		return fmt.Errorf("error when trying to open action item file %s: %v", actionItemFilePath, err)
+		return "", fmt.Errorf("error when trying to open action item file %s: %v", actionItemFilePath, err)
 	}
 
 	aiBytes, err := io.ReadAll(aiInput)
 	if err != nil {
-		return fmt.Errorf("could not read action item file %s: %v", actionItemFilePath, err)
+		return "", fmt.Errorf("could not read action item file %s: %v", actionItemFilePath, err)
 	}
 
 	var ai actionItem
 	err = yaml.Unmarshal(aiBytes, &ai)
 	if err != nil {
-		return fmt.Errorf("could not parse action item file %s: %v", actionItemFilePath, err)
+		return "", fmt.Errorf("could not parse action item file %s: %v", actionItemFilePath, err)
 	}
 
 	err = validateInputActionItem(insightsContext, ai)
 	if err != nil {
-		return fmt.Errorf("invalid input action item: %v", err)
+		return "", fmt.Errorf("invalid input action item: %v", err)
 	}
 
 	ruleInput, err := os.Open(automationRuleFilePath)
 	if err != nil {
-		return fmt.Errorf("error when trying to open file %s: %v", automationRuleFilePath, err)
+		return "", fmt.Errorf("error when trying to open file %s: %v", automationRuleFilePath, err)
 	}
 
 	ruleBytes, err := io.ReadAll(ruleInput)
 	if err != nil {
-		return fmt.Errorf("could not read rule file %s: %v", automationRuleFilePath, err)
+		return "", fmt.Errorf("could not read rule file %s: %v", automationRuleFilePath, err)
 	}
 
 	verifyRule := verifyRule{
@@ -204,9 +224,15 @@ func ValidateRule(client *req.Client, org, automationRuleFilePath, actionItemFil
 		ReportType: *ai.ReportType,
 		Script:     string(ruleBytes),
 	}
-	r, err := runVerifyRule(client, org, verifyRule, dryRun)
+	var r *verifyWithEvents
+	var calls []integrationCall
+	if offline {
+		r, calls, err = runVerifyRuleOffline(verifyRule, dryRun)
+	} else {
+		r, err = runVerifyRule(client, org, verifyRule, dryRun)
+	}
 	if err != nil {
-		return fmt.Errorf("unable to verify rule: %v", err)
+		return "", fmt.Errorf("unable to verify rule: %v", err)
 	}
 	responseActionItem := r.ActionItem
 
@@ -217,48 +243,134 @@ func ValidateRule(client *req.Client, org, automationRuleFilePath, actionItemFil
 		}
 	}
 
+	if len(calls) > 0 {
+		fmt.Printf("\n-- Integration Calls --\n\n")
+		b, err := yaml.Marshal(calls)
+		if err != nil {
+			return "", fmt.Errorf("could not marshal integration calls: %v", err)
+		}
+		fmt.Println(string(b))
+	}
+
 	if expectedActionItemFilePath == "" {
 		fmt.Printf("\n-- Returned Action Item --\n\n")
 		b, err := yaml.Marshal(responseActionItem)
 		if err != nil {
-			return fmt.Errorf("could not marshal verify result: %v", err)
+			return "", fmt.Errorf("could not marshal verify result: %v", err)
 		}
 		fmt.Println(string(b))
-		return nil
+		return "", nil
 	}
 
 	expectedActionItemFile, err := os.Open(expectedActionItemFilePath)
 	if err != nil {
-		return fmt.Errorf("error when trying to open expected file %s: %v", expectedActionItemFilePath, err)
+		return "", fmt.Errorf("error when trying to open expected file %s: %v", expectedActionItemFilePath, err)
 	}
 
 	expectedActionItemBytes, err := io.ReadAll(expectedActionItemFile)
 	if err != nil {
-		return fmt.Errorf("failed to read output file: %v", err)
+		return "", fmt.Errorf("failed to read output file: %v", err)
 	}
 
 	fmt.Printf("\n-- Diff Result --\n\n")
 
 	opts, err := buildCmpOptions(expectedActionItemBytes)
 	if err != nil {
-		return fmt.Errorf("could not build cmp options: %v", err)
+		return "", fmt.Errorf("could not build cmp options: %v", err)
 	}
 
 	var expectedActionItem actionItem
 	err = yaml.Unmarshal(expectedActionItemBytes, &expectedActionItem)
 	if err != nil {
-		return fmt.Errorf("could not marshal expected response: %v", err)
+		return "", fmt.Errorf("could not marshal expected response: %v", err)
 	}
 
 	diff := cmp.Diff(expectedActionItem, responseActionItem, opts...)
-	if len(diff) == 0 {
-		logrus.Infoln("Success - actual response matches expected response")
-		fmt.Println()
-	} else {
+	if len(diff) > 0 {
 		logrus.Errorln("Test failed:")
 		fmt.Println(diff)
+		return diff, errors.New("actual response does not match expected response")
 	}
-	return nil
+	logrus.Infoln("Success - actual response matches expected response")
+	fmt.Println()
+	return "", nil
+}
+
+const (
+	batchInputSuffix    = ".input.yaml"
+	batchExpectedSuffix = ".expected.yaml"
+)
+
+// RunBatch validates each automation rule .js file in batchDir with its test
+// cases. A case named <case> of the rule <rule>.js is an action item file
+// named <rule>.<case>.input.yaml, and the action item the rule is expected
+// to produce from it, in a file named <rule>.<case>.expected.yaml.
+// This is meant to be called from a cobra.Command{}.
+func RunBatch(client *req.Client, org, batchDir, insightsContext string, dryRun, offline bool) (successfulCases, failedCases []string, err error) {
+	var ruleFiles []string
+	err = filepath.WalkDir(batchDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".js" {
+			ruleFiles = append(ruleFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list .js files: %v", err)
+	}
+	for _, ruleFile := range ruleFiles {
+		caseNames, err := findRuleCases(ruleFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error finding test cases for automation rule %s: %w", ruleFile, err)
+		}
+		if len(caseNames) == 0 {
+			logrus.Errorf("No %s action item files found to use as input for validation of automation rule %s", batchInputSuffix, ruleFile)
+			report.Record(report.TestCase{Suite: ruleFile, File: ruleFile, Failure: fmt.Sprintf("no %s action item files found to use as input", batchInputSuffix)})
+			failedCases = append(failedCases, ruleFile)
+			continue
+		}
+		base := strings.TrimSuffix(ruleFile, ".js")
+		for _, caseName := range caseNames {
+			testCase := base + "." + caseName
+			logrus.Infof("Validating automation rule %s with case %s", ruleFile, caseName)
+			err := ValidateRule(client, org, ruleFile, testCase+batchInputSuffix, testCase+batchExpectedSuffix, insightsContext, dryRun, offline)
+			if err != nil {
+				logrus.Errorf("Failed validation of automation rule %s with case %s: %v\n", ruleFile, caseName, err)
+				failedCases = append(failedCases, testCase)
+				continue
+			}
+			successfulCases = append(successfulCases, testCase)
+		}
+	}
+	if len(failedCases) > 0 {
+		return successfulCases, failedCases, fmt.Errorf("%d failed and %d succeeded", len(failedCases), len(successfulCases))
+	}
+	return successfulCases, failedCases, nil
+}
+
+// findRuleCases returns the names of the test cases of ruleFile, from the
+// names of the input files next to it.
+func findRuleCases(ruleFile string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(ruleFile))
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(filepath.Base(ruleFile), ".js") + "."
+	var caseNames []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, batchInputSuffix) {
+			continue
+		}
+		caseName := strings.TrimSuffix(strings.TrimPrefix(name, prefix), batchInputSuffix)
+		if caseName == "" || strings.Contains(caseName, ".") {
+			continue
+		}
+		caseNames = append(caseNames, caseName)
+	}
+	return caseNames, nil
 }
 
 func validateInputActionItem(context string, ai actionItem) error {
@@ -0,0 +1,203 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package state records what was last pushed to Insights, so that a push can
+// skip resource types whose local content has not changed since, and which
+// resources were pushed with an owner.
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/fairwindsops/insights-cli/pkg/variables"
+)
+
+// fileNameRegex matches the characters of an organization name that are
+// replaced in state file names.
+var fileNameRegex = regexp.MustCompile("[^A-Za-z0-9_-]+")
+
+// FileName returns the name of the state file recording pushes of pushDir to
+// org on the Insights instance at hostname, which is unique to the hostname,
+// the organization and the absolute path of pushDir.
+func FileName(hostname, org, pushDir string) (string, error) {
+	absPushDir, err := filepath.Abs(pushDir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(hostname + "\x00" + org + "\x00" + absPushDir))
+	return fmt.Sprintf("%s-%s.json", fileNameRegex.ReplaceAllString(org, "-"), hex.EncodeToString(sum[:8])), nil
+}
+
+// skipFile is skipped by HashPath, so that a state file within the push
+// directory does not change the hash of the content it records.
+var skipFile string
+
+// SkipFile sets a file, such as the state file, that HashPath skips.
+func SkipFile(fileName string) {
+	skipFile = ""
+	if fileName == "" {
+		return
+	}
+	if abs, err := filepath.Abs(fileName); err == nil {
+		skipFile = abs
+	}
+}
+
+// formatVersion is incremented when the state file format changes
+// incompatibly.
+const formatVersion = 1
+
+// State holds the last push of each resource type to an organization.
+type State struct {
+	FormatVersion int                 `json:"formatVersion"`
+	Hostname      string              `json:"hostname"`
+	Organization  string              `json:"organization"`
+	Resources     map[string]Resource `json:"resources"`
+	// Owner is the owner the resources in Owned were pushed with.
+	Owner string `json:"owner,omitempty"`
+	// Owned holds, by resource type, the names of the resources pushed with
+	// Owner.
+	Owned map[string][]string `json:"owned,omitempty"`
+}
+
+// Resource records the push of one resource type: a hash of the local content
+// and push options, and the fingerprint of the resource type in Insights
+// after the push.
+type Resource struct {
+	LocalHash         string    `json:"localHash"`
+	RemoteFingerprint string    `json:"remoteFingerprint"`
+	PushedAt          time.Time `json:"pushedAt"`
+}
+
+// New returns an empty state for org on the Insights instance at hostname.
+func New(hostname, org string) *State {
+	return &State{FormatVersion: formatVersion, Hostname: hostname, Organization: org, Resources: map[string]Resource{}}
+}
+
+// Load reads the state file fileName. An empty state is returned if the file
+// does not exist, or was written for another Insights instance or
+// organization, or by an incompatible version.
+func Load(fileName, hostname, org string) (*State, error) {
+	b, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(hostname, org), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse state file %s: %w", fileName, err)
+	}
+	if s.FormatVersion != formatVersion || s.Hostname != hostname || s.Organization != org || s.Resources == nil {
+		return New(hostname, org), nil
+	}
+	return &s, nil
+}
+
+// Save writes the state as JSON to fileName.
+func (s *State) Save(fileName string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, b, 0644)
+}
+
+// Unchanged returns true if resourceType was last pushed with the same
+// localHash.
+func (s *State) Unchanged(resourceType, localHash string) bool {
+	r, ok := s.Resources[resourceType]
+	return ok && r.LocalHash == localHash
+}
+
+// RemoteChanged returns true if the fingerprint of resourceType in Insights no
+// longer matches the one recorded after it was last pushed.
+func (s *State) RemoteChanged(resourceType, remoteFingerprint string) bool {
+	r, ok := s.Resources[resourceType]
+	return ok && r.RemoteFingerprint != remoteFingerprint
+}
+
+// Record records a push of resourceType.
+func (s *State) Record(resourceType, localHash, remoteFingerprint string) {
+	s.Resources[resourceType] = Resource{LocalHash: localHash, RemoteFingerprint: remoteFingerprint, PushedAt: time.Now().UTC()}
+}
+
+// OwnedBy returns the names of the resources of resourceType recorded as
+// pushed with owner.
+func (s *State) OwnedBy(owner, resourceType string) []string {
+	if owner == "" || s.Owner != owner {
+		return nil
+	}
+	return s.Owned[resourceType]
+}
+
+// RecordOwned records names as the resources of resourceType pushed with
+// owner. Resources recorded with another owner are forgotten.
+func (s *State) RecordOwned(owner, resourceType string, names []string) {
+	if s.Owner != owner || s.Owned == nil {
+		s.Owner, s.Owned = owner, map[string][]string{}
+	}
+	names = slices.Clone(names)
+	slices.Sort(names)
+	s.Owned[resourceType] = slices.Compact(names)
+}
+
+// HashPath returns a hash of the names and content of the files in path,
+// other than the file set by SkipFile, which may be a file or a directory,
+// and of options, such as push flags that
+// change what a push of path does. Variables are expanded in the content, so
+// that changing their values changes the hash.
+func HashPath(path string, options ...string) (string, error) {
+	h := sha256.New()
+	for _, o := range options {
+		fmt.Fprintf(h, "option %q\n", o)
+	}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if skipFile != "" {
+			if abs, err := filepath.Abs(p); err == nil && abs == skipFile {
+				return nil
+			}
+		}
+		b, err := variables.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file %q %d\n", filepath.ToSlash(rel), len(b))
+		h.Write(b)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
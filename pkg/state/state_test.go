@@ -0,0 +1,109 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPath(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "check"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "check", "policy.rego"), []byte("package fairwinds"), 0644))
+
+	h1, err := HashPath(dir, "delete=false")
+	assert.NoError(t, err)
+	h2, err := HashPath(dir, "delete=false")
+	assert.NoError(t, err)
+	assert.Equal(t, h1, h2)
+
+	h3, err := HashPath(dir, "delete=true")
+	assert.NoError(t, err)
+	assert.NotEqual(t, h1, h3)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "check", "instance.yaml"), []byte("targets: []"), 0644))
+	h4, err := HashPath(dir, "delete=false")
+	assert.NoError(t, err)
+	assert.NotEqual(t, h1, h4)
+
+	file, err := HashPath(filepath.Join(dir, "check", "policy.rego"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, file)
+
+	stateFile := filepath.Join(dir, ".insights-state.json")
+	SkipFile(stateFile)
+	defer SkipFile("")
+	assert.NoError(t, os.WriteFile(stateFile, []byte("{}"), 0644))
+	h5, err := HashPath(dir, "delete=false")
+	assert.NoError(t, err)
+	assert.Equal(t, h4, h5, "the state file is skipped")
+}
+
+func TestFileName(t *testing.T) {
+	a, err := FileName("https://insights.fairwinds.com", "acme-co", "policies")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^acme-co-[0-9a-f]{16}\.json$`, a)
+	b, err := FileName("https://insights.fairwinds.com", "acme-co", "other-policies")
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	c, err := FileName("https://insights.fairwinds.com", "other-co", "policies")
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, c)
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	d, err := FileName("https://insights.fairwinds.com", "acme-co", filepath.Join(cwd, "policies"))
+	assert.NoError(t, err)
+	assert.Equal(t, a, d, "relative and absolute push directories share a state file")
+	e, err := FileName("https://staging.example.com", "acme-co", "policies")
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, e, "organizations of the same name on different hosts do not share a state file")
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "state.json")
+	s, err := Load(fileName, "https://insights.fairwinds.com", "acme-co")
+	assert.NoError(t, err)
+	assert.Empty(t, s.Resources)
+
+	s.Record("opa", "local", "remote")
+	assert.NoError(t, s.Save(fileName))
+
+	s, err = Load(fileName, "https://insights.fairwinds.com", "acme-co")
+	assert.NoError(t, err)
+	assert.True(t, s.Unchanged("opa", "local"))
+	assert.False(t, s.Unchanged("opa", "other"))
+	assert.False(t, s.Unchanged("rules", "local"))
+	assert.False(t, s.RemoteChanged("opa", "remote"))
+	assert.True(t, s.RemoteChanged("opa", "edited"))
+	assert.False(t, s.RemoteChanged("rules", "edited"))
+
+	s, err = Load(fileName, "https://insights.fairwinds.com", "other-org")
+	assert.NoError(t, err)
+	assert.Empty(t, s.Resources)
+
+	s, err = Load(fileName, "https://staging.example.com", "acme-co")
+	assert.NoError(t, err)
+	assert.Empty(t, s.Resources, "state of another host is not loaded")
+}
+
+func TestOwned(t *testing.T) {
+	s := New("https://insights.fairwinds.com", "acme-co")
+	assert.Empty(t, s.OwnedBy("acme/policies", "rules"))
+	s.RecordOwned("acme/policies", "rules", []string{"b", "a", "b"})
+	s.RecordOwned("acme/policies", "opa", []string{"check"})
+	assert.Equal(t, []string{"a", "b"}, s.OwnedBy("acme/policies", "rules"))
+	assert.Empty(t, s.OwnedBy("acme/other", "rules"))
+	assert.Empty(t, s.OwnedBy("", "rules"))
+
+	fileName := filepath.Join(t.TempDir(), "state.json")
+	assert.NoError(t, s.Save(fileName))
+	loaded, err := Load(fileName, "https://insights.fairwinds.com", "acme-co")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"check"}, loaded.OwnedBy("acme/policies", "opa"))
+
+	loaded.RecordOwned("acme/other", "rules", []string{"c"})
+	assert.Empty(t, loaded.OwnedBy("acme/other", "opa"), "resources of another owner are forgotten")
+	assert.Equal(t, []string{"c"}, loaded.OwnedBy("acme/other", "rules"))
+}
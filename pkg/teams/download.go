@@ -0,0 +1,45 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teams
+
+import (
+	"os"
+
+	"github.com/imroc/req/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// DownloadTeams writes the teams in Insights to teams.yaml in saveDir, which
+// BuildPlan reads back. It returns the number of teams written.
+func DownloadTeams(client *req.Client, org, saveDir string) (int, error) {
+	teams, err := ListTeams(client, org)
+	if err != nil {
+		return 0, err
+	}
+	err = writeTeamsFile(saveDir+"/teams.yaml", teams)
+	if err != nil {
+		return 0, err
+	}
+	return len(teams), nil
+}
+
+// writeTeamsFile writes teams in the format read by readTeamsFile.
+func writeTeamsFile(teamsFileName string, teams []TeamOutput) error {
+	b, err := yaml.Marshal(teams)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(teamsFileName, b, 0644)
+}
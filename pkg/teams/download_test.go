@@ -0,0 +1,48 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadTeamsRoundTrip(t *testing.T) {
+	remoteTeams := []TeamOutput{
+		{
+			Name:       "platform",
+			Clusters:   []string{"prod", "staging"},
+			Namespaces: []string{"kube-system"},
+			AppGroups:  []string{"core"},
+		},
+		{
+			Name:                   "web",
+			Repositories:           []string{"acme/web"},
+			DisallowedClusters:     []string{"prod"},
+			DisallowedNamespaces:   []string{},
+			DisallowedRepositories: []string{"acme/secret"},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v0/organizations/acme-co/teams", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(remoteTeams))
+	}))
+	defer server.Close()
+	client := req.C().SetBaseURL(server.URL)
+
+	dir := t.TempDir()
+	count, err := DownloadTeams(client, "acme-co", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	plan, err := BuildPlan(client, dir, "acme-co", true)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Inserts)
+	assert.Empty(t, plan.Updates)
+	assert.Empty(t, plan.Deletes)
+	assert.Len(t, plan.Teams, 2)
+}
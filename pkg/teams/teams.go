@@ -19,11 +19,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/imroc/req/v3"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"sigs.k8s.io/yaml"
+
+	"github.com/fairwindsops/insights-cli/pkg/deletion"
+	"github.com/fairwindsops/insights-cli/pkg/utils"
+	"github.com/fairwindsops/insights-cli/pkg/variables"
 )
 
 const teamsPutURLFormat = "/v0/organizations/%s/teams-bulk"
@@ -42,7 +47,37 @@ type TeamInput struct {
 
 type TeamOutput = TeamInput
 
+// PostTeams posts the teams configuration to Insights. If
+// deleteNonProvidedTeams is true, teams in Insights that are not given are
+// deleted, once confirmed.
 func PostTeams(client *req.Client, teamInput []TeamInput, deleteNonProvidedTeams bool, org string) error {
+	if deleteNonProvidedTeams {
+		remoteTeams, err := ListTeams(client, org)
+		if err != nil {
+			return fmt.Errorf("error listing teams: %w", err)
+		}
+		err = deletion.Confirm("teams", missingTeams(teamInput, remoteTeams), false)
+		if err != nil {
+			return err
+		}
+	}
+	return postTeams(client, teamInput, deleteNonProvidedTeams, org)
+}
+
+// missingTeams returns the names of the remote teams that are not in
+// localTeams.
+func missingTeams(localTeams []TeamInput, remoteTeams []TeamOutput) []string {
+	localTeamsByName := lo.KeyBy(localTeams, func(i TeamInput) string { return i.Name })
+	var names []string
+	for _, team := range remoteTeams {
+		if _, found := localTeamsByName[team.Name]; !found {
+			names = append(names, team.Name)
+		}
+	}
+	return names
+}
+
+func postTeams(client *req.Client, teamInput []TeamInput, deleteNonProvidedTeams bool, org string) error {
 	url := fmt.Sprintf(teamsPutURLFormat, org)
 	if deleteNonProvidedTeams {
 		url += "?deleteNonProvidedTeams=true"
@@ -74,93 +109,133 @@ func ListTeams(client *req.Client, org string) ([]TeamOutput, error) {
 	return teams, nil
 }
 
-func PushTeams(client *req.Client, pushDir, org string, deleteNonProvidedTeams, dryRun bool) error {
-	if pushDir == "" {
-		return errors.New("pushDir cannot be empty")
-	}
-
-	teamsFileName := pushDir + "/teams.yaml"
-	logrus.Infof("Pushing teams configuration from %s", teamsFileName)
+// readTeamsFile reads the teams configuration from the given file.
+func readTeamsFile(teamsFileName string) ([]TeamInput, error) {
 	_, err := os.Stat(teamsFileName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	teamsFile, err := os.Open(teamsFileName)
+	localTeams := []TeamInput{}
+	b, err := variables.ReadFile(teamsFileName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = yaml.Unmarshal(b, &localTeams)
+	if err != nil {
+		return nil, err
 	}
+	return localTeams, nil
+}
 
-	defer func() {
-		if err := teamsFile.Close(); err != nil {
-			logrus.Errorf("error closing teams file: %v", err)
-		}
-	}()
+// Plan holds the teams configuration that will be posted by ApplyPlan, the
+// names of the teams that will be created, updated or deleted, and a
+// fingerprint of the teams in Insights at the time the plan was built.
+type Plan struct {
+	Teams                  []TeamInput `json:"teams"`
+	Inserts                []string    `json:"inserts"`
+	Updates                []string    `json:"updates"`
+	Deletes                []string    `json:"deletes"`
+	DeleteNonProvidedTeams bool        `json:"deleteNonProvidedTeams"`
+	RemoteFingerprint      string      `json:"remoteFingerprint"`
+}
 
-	localTeams := []TeamInput{}
-	b, err := os.ReadFile(teamsFileName)
+// BuildPlan compares the teams.yaml file in pushDir with the teams in
+// Insights.
+func BuildPlan(client *req.Client, pushDir, org string, deleteNonProvidedTeams bool) (*Plan, error) {
+	if pushDir == "" {
+		return nil, errors.New("pushDir cannot be empty")
+	}
+	localTeams, err := readTeamsFile(pushDir + "/teams.yaml")
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	err = yaml.Unmarshal(b, &localTeams)
+	remoteTeams, err := ListTeams(client, org)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error listing teams: %w", err)
 	}
-
-	if dryRun {
-		logrus.Infof("Dry run: Would have pushed the following teams configuration:")
-		for _, team := range localTeams {
-			logrus.Infof("Team: %s", team.Name)
-			logrus.Infof("  Clusters: %v", team.Clusters)
-			logrus.Infof("  Namespaces: %v", team.Namespaces)
-			logrus.Infof("  Repositories: %v", team.Repositories)
-			logrus.Infof("  DisallowedClusters: %v", team.DisallowedClusters)
-			logrus.Infof("  DisallowedNamespaces: %v", team.DisallowedNamespaces)
-			logrus.Infof("  DisallowedRepositories: %v", team.DisallowedRepositories)
-			logrus.Infof("  AppGroups: %v", team.AppGroups)
+	fingerprint, err := utils.Fingerprint(remoteTeams)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fingerprint teams: %w", err)
+	}
+	plan := Plan{Teams: localTeams, DeleteNonProvidedTeams: deleteNonProvidedTeams, RemoteFingerprint: fingerprint}
+	remoteTeamsByName := lo.KeyBy(remoteTeams, func(i TeamOutput) string { return i.Name })
+	for _, team := range localTeams {
+		remoteTeam, found := remoteTeamsByName[team.Name]
+		if !found {
+			plan.Inserts = append(plan.Inserts, team.Name)
+			continue
 		}
-
-		if deleteNonProvidedTeams {
-			logrus.Infof("--------------------------------")
-
-			remoteTeams, err := ListTeams(client, org)
-			if err != nil {
-				return fmt.Errorf("error listing teams: %w", err)
-			}
-			remoteTeamsByName := lo.KeyBy(remoteTeams, func(i TeamOutput) string { return i.Name })
-			localTeamsByName := lo.KeyBy(localTeams, func(i TeamInput) string { return i.Name })
-
-			teamsToBeDeleted := []string{}
-			for name := range remoteTeamsByName {
-				if _, ok := localTeamsByName[name]; !ok {
-					teamsToBeDeleted = append(teamsToBeDeleted, name)
-				}
-			}
-
-			if len(teamsToBeDeleted) == 0 {
-				logrus.Infof("Dry run: Would have deleted no teams")
-				return nil
-			}
-
-			logrus.Infof("Dry run: Would have deleted the following teams:")
-			for _, team := range teamsToBeDeleted {
-				logrus.Infof("Team: %s", team)
-			}
+		if !teamsEqual(team, remoteTeam) {
+			plan.Updates = append(plan.Updates, team.Name)
 		}
+	}
+	if deleteNonProvidedTeams {
+		plan.Deletes = missingTeams(localTeams, remoteTeams)
+	}
+	return &plan, nil
+}
 
-		return nil
+// teamsEqual treats nil and empty lists as equal, as the API returns empty
+// lists for fields omitted from teams.yaml.
+func teamsEqual(a, b TeamInput) bool {
+	return a.Name == b.Name &&
+		slices.Equal(a.Clusters, b.Clusters) &&
+		slices.Equal(a.Namespaces, b.Namespaces) &&
+		slices.Equal(a.Repositories, b.Repositories) &&
+		slices.Equal(a.DisallowedClusters, b.DisallowedClusters) &&
+		slices.Equal(a.DisallowedNamespaces, b.DisallowedNamespaces) &&
+		slices.Equal(a.DisallowedRepositories, b.DisallowedRepositories) &&
+		slices.Equal(a.AppGroups, b.AppGroups)
+}
+
+// RemoteFingerprint returns the fingerprint of the teams in Insights
+// covered by the plan, which is compared with plan.RemoteFingerprint.
+func RemoteFingerprint(client *req.Client, org string, plan Plan) (string, error) {
+	remoteTeams, err := ListTeams(client, org)
+	if err != nil {
+		return "", fmt.Errorf("error listing teams: %w", err)
 	}
+	fingerprint, err := utils.Fingerprint(remoteTeams)
+	if err != nil {
+		return "", fmt.Errorf("unable to fingerprint teams: %w", err)
+	}
+	return fingerprint, nil
+}
 
-	err = PostTeams(client, localTeams, deleteNonProvidedTeams, org)
+// VerifyPlan returns an error if the teams in Insights have changed since the
+// plan was built.
+func VerifyPlan(client *req.Client, org string, plan Plan) error {
+	fingerprint, err := RemoteFingerprint(client, org, plan)
 	if err != nil {
 		return err
 	}
-
-	logrus.Debugln("Done pushing teams configuration")
+	if fingerprint != plan.RemoteFingerprint {
+		return errors.New("teams in Insights have changed since the plan was created")
+	}
 	return nil
 }
 
+// ApplyPlan posts the teams configuration described by the plan to Insights.
+func ApplyPlan(client *req.Client, org string, plan Plan, dryRun bool) error {
+	for _, name := range plan.Inserts {
+		logrus.Infof("Adding team: %s", name)
+	}
+	for _, name := range plan.Updates {
+		logrus.Infof("Updating team: %s", name)
+	}
+	for _, name := range plan.Deletes {
+		logrus.Infof("Deleting team: %s", name)
+	}
+	err := deletion.Confirm("teams", plan.Deletes, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	return postTeams(client, plan.Teams, plan.DeleteNonProvidedTeams, org)
+}
+
 func getHeaders() map[string]string {
 	return map[string]string{
 		"Content-Type": "application/yaml",
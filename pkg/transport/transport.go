@@ -0,0 +1,195 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transport configures how requests to Insights are retried and
+// timed out.
+package transport
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Options configures retries and timeouts of requests to Insights.
+type Options struct {
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// retries. A longer wait asked for with Retry-After is capped at
+	// MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// RequestTimeout limits each attempt of a request. Zero means no limit.
+	RequestTimeout time.Duration
+	// Deadline limits the time spent on all requests, including retries.
+	// Zero means no limit.
+	Deadline time.Duration
+}
+
+// DefaultOptions returns the options used when none are configured.
+func DefaultOptions() Options {
+	return Options{
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// Configure applies the options to every request made with client. The
+// returned function releases the overall deadline, if there is one.
+func Configure(client *req.Client, o Options) context.CancelFunc {
+	client.SetTimeout(o.RequestTimeout)
+	client.SetCommonRetryCount(o.MaxRetries)
+	client.SetCommonRetryCondition(shouldRetry)
+	client.SetCommonRetryInterval(retryInterval(o.MinBackoff, o.MaxBackoff))
+	client.SetCommonRetryHook(func(resp *req.Response, err error) {
+		if err != nil {
+			logrus.Debugf("Retrying request to Insights after error: %v", err)
+			return
+		}
+		logrus.Debugf("Retrying %s %s after response code %d", resp.Request.Method, resp.Request.RawURL, resp.StatusCode)
+	})
+	client.WrapRoundTripFunc(logRequest)
+	if o.Deadline <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), o.Deadline)
+	client.OnBeforeRequest(func(_ *req.Client, r *req.Request) error {
+		if r.Context() == context.Background() {
+			r.SetContext(ctx)
+		}
+		return nil
+	})
+	return cancel
+}
+
+// sensitiveHeaders are redacted when requests are logged.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// logRequest logs each request and its headers at trace level, with the
+// values of sensitiveHeaders, such as the Insights token, redacted.
+func logRequest(rt req.RoundTripper) req.RoundTripFunc {
+	return func(r *req.Request) (*req.Response, error) {
+		if logrus.IsLevelEnabled(logrus.TraceLevel) {
+			logrus.Tracef("Request %s %s with headers %v", r.Method, r.RawURL, redactHeaders(r.Headers))
+		}
+		return rt.RoundTrip(r)
+	}
+}
+
+// redactHeaders returns a copy of headers with the values of
+// sensitiveHeaders replaced, so they can be logged.
+func redactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, name := range sensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+// shouldRetry retries idempotent requests that failed to get a response or
+// got a gateway error, and any request that was rate limited, as a rate
+// limited request has not been processed.
+func shouldRetry(resp *req.Response, err error) bool {
+	if resp == nil || resp.Request == nil {
+		return err != nil
+	}
+	if resp.Request.Context().Err() != nil {
+		return false
+	}
+	if resp.Response != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !isIdempotent(resp.Request.Method) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.GetStatusCode() {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryInterval waits as long as a Retry-After header asks, up to
+// maxBackoff, otherwise backs off exponentially from minBackoff up to
+// maxBackoff, with jitter. The wait never extends past the deadline of the
+// request.
+func retryInterval(minBackoff, maxBackoff time.Duration) req.GetRetryIntervalFunc {
+	return func(resp *req.Response, attempt int) time.Duration {
+		now := time.Now()
+		wait, ok := retryAfter(resp, now)
+		if ok {
+			wait = min(wait, maxBackoff)
+		} else {
+			wait = backoff(minBackoff, maxBackoff, attempt)
+		}
+		if resp != nil && resp.Request != nil {
+			if deadline, ok := resp.Request.Context().Deadline(); ok {
+				wait = min(wait, max(deadline.Sub(now), 0))
+			}
+		}
+		return wait
+	}
+}
+
+func backoff(minBackoff, maxBackoff time.Duration, attempt int) time.Duration {
+	wait := minBackoff
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, maxBackoff)
+	if wait <= 1 {
+		return wait
+	}
+	half := wait / 2
+	return half + rand.N(wait-half)
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date.
+func retryAfter(resp *req.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || resp.Response == nil {
+		return 0, false
+	}
+	value := resp.GetHeader("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func testOptions() Options {
+	return Options{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, RequestTimeout: time.Second}
+}
+
+func TestRetriesGatewayErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := req.C()
+	cancel := Configure(client, testOptions())
+	defer cancel()
+
+	resp, err := client.R().Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	resp, err = client.R().Post(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetriesRateLimitedPost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	client := req.C()
+	cancel := Configure(client, testOptions())
+	defer cancel()
+
+	resp, err := client.R().Post(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := req.C()
+	o := testOptions()
+	o.MaxRetries = 100
+	o.MinBackoff, o.MaxBackoff = 20*time.Millisecond, 20*time.Millisecond
+	o.Deadline = 50 * time.Millisecond
+	cancel := Configure(client, o)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.R().Get(server.URL)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	resp := func(value string) *req.Response {
+		r := &req.Response{Response: &http.Response{Header: http.Header{}}}
+		if value != "" {
+			r.Header.Set("Retry-After", value)
+		}
+		return r
+	}
+	_, ok := retryAfter(resp(""), now)
+	assert.False(t, ok)
+	wait, ok := retryAfter(resp("7"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, wait)
+	wait, ok = retryAfter(resp(now.Add(time.Minute).Format(http.TimeFormat)), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, wait)
+	_, ok = retryAfter(resp("soon"), now)
+	assert.False(t, ok)
+}
+
+func TestRetryIntervalCapsRetryAfter(t *testing.T) {
+	resp := &req.Response{Response: &http.Response{Header: http.Header{}}, Request: req.C().R()}
+	resp.Header.Set("Retry-After", "600")
+	interval := retryInterval(time.Millisecond, 2*time.Second)
+	assert.Equal(t, 2*time.Second, interval(resp, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	resp.Request = req.C().R().SetContext(ctx)
+	assert.LessOrEqual(t, interval(resp, 1), 100*time.Millisecond)
+}
+
+func TestDefaultOptionsHaveNoRequestTimeout(t *testing.T) {
+	assert.Zero(t, DefaultOptions().RequestTimeout)
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt < 40; attempt++ {
+		wait := backoff(100*time.Millisecond, time.Second, attempt)
+		assert.LessOrEqual(t, wait, time.Second)
+		assert.GreaterOrEqual(t, wait, 50*time.Millisecond)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer secret-token")
+	headers.Set("Accept", "application/json")
+	redacted := redactHeaders(headers)
+	assert.Equal(t, "REDACTED", redacted.Get("Authorization"))
+	assert.Equal(t, "application/json", redacted.Get("Accept"))
+	assert.Equal(t, "Bearer secret-token", headers.Get("Authorization"))
+	assert.NotContains(t, fmt.Sprint(redacted), "secret-token")
+}
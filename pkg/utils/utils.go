@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
 	"go.yaml.in/yaml/v3"
 )
 
@@ -28,3 +32,14 @@ func InvertBoolPointer(b *bool) *bool {
 func GetYamlBytes[T any](e T) ([]byte, error) {
 	return yaml.Marshal(e)
 }
+
+// Fingerprint returns a stable sha256 hex digest of the JSON representation of
+// e. It is used to detect whether remote state changed between two reads.
+func Fingerprint[T any](e T) (string, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package variables expands ${NAME} references in the YAML resource files read
+// by push, so that files can be shared between environments. Other files,
+// such as automation rule scripts and rego, are read as they are.
+//
+// A reference may give a default, as ${NAME:-default}, which is used when
+// NAME has no value. References to names without a value or default are left
+// as they are. $${NAME} is replaced by ${NAME} without expansion.
+package variables
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var referenceRegex = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+var current map[string]string
+
+// Set sets the values of the variables expanded by Expand and ReadFile.
+func Set(values map[string]string) {
+	current = values
+}
+
+// Expand returns content with the references to variables replaced by their
+// values.
+func Expand(content []byte) []byte {
+	if current == nil {
+		return content
+	}
+	return referenceRegex.ReplaceAllFunc(content, func(reference []byte) []byte {
+		if reference[1] == '$' {
+			return reference[1:]
+		}
+		match := referenceRegex.FindSubmatch(reference)
+		if value, ok := current[string(match[1])]; ok {
+			return []byte(value)
+		}
+		if match[2] != nil {
+			return match[3]
+		}
+		logrus.Debugf("leaving %s unexpanded, as %s has no value", reference, match[1])
+		return reference
+	})
+}
+
+// ReadFile returns the content of the file at path, with variables expanded if
+// it is a YAML file.
+func ReadFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+		return content, nil
+	}
+	return Expand(content), nil
+}
+
+// LoadFile reads the values of variables from a YAML file mapping names to
+// values.
+func LoadFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	err = yaml.Unmarshal(content, &values)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing values file %s: %w", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,51 @@
+package variables
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	assert.Equal(t, "cluster: ${CLUSTER}", string(Expand([]byte("cluster: ${CLUSTER}"))), "no values set")
+
+	Set(map[string]string{"CLUSTER": "prod", "SEVERITY": "0.9"})
+	defer Set(nil)
+	assert.Equal(t, "cluster: prod\nseverity: 0.9\n", string(Expand([]byte("cluster: ${CLUSTER}\nseverity: ${SEVERITY}\n"))))
+	assert.Equal(t, "channel: alerts", string(Expand([]byte("channel: ${CHANNEL:-alerts}"))))
+	assert.Equal(t, "cluster: prod", string(Expand([]byte("cluster: ${CLUSTER:-staging}"))))
+	assert.Equal(t, "cluster: ${CLUSTER}", string(Expand([]byte("cluster: $${CLUSTER}"))))
+	assert.Equal(t, "`${title} ${ActionItem.Title}`", string(Expand([]byte("`${title} ${ActionItem.Title}`"))))
+}
+
+func TestReadFile(t *testing.T) {
+	Set(map[string]string{"CLUSTER": "prod"})
+	defer Set(nil)
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "rule.yaml")
+	assert.NoError(t, os.WriteFile(yamlPath, []byte("cluster: ${CLUSTER}\nliteral: $${CLUSTER}\n"), 0644))
+	content, err := ReadFile(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "cluster: prod\nliteral: ${CLUSTER}\n", string(content))
+
+	scriptPath := filepath.Join(dir, "rule.js")
+	script := "ActionItem.Notes = `${CLUSTER} $${CLUSTER}`;\n"
+	assert.NoError(t, os.WriteFile(scriptPath, []byte(script), 0644))
+	content, err = ReadFile(scriptPath)
+	assert.NoError(t, err)
+	assert.Equal(t, script, string(content), "scripts are read as they are")
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("CLUSTER: prod\nSEVERITY: 0.9\nBLOCK: true\n"), 0644))
+	values, err := LoadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"CLUSTER": "prod", "SEVERITY": "0.9", "BLOCK": "true"}, values)
+
+	assert.NoError(t, os.WriteFile(path, []byte("CLUSTERS:\n  prod: true\n"), 0644))
+	_, err = LoadFile(path)
+	assert.Error(t, err)
+}
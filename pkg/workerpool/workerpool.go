@@ -0,0 +1,72 @@
+// Copyright 2026 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package workerpool runs calls to Insights with bounded parallelism.
+package workerpool
+
+import (
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+var concurrency = 1
+
+// SetConcurrency sets the number of calls made at once by callers that use
+// Concurrency. Values below 1 are treated as 1.
+func SetConcurrency(n int) {
+	concurrency = max(n, 1)
+}
+
+// Concurrency returns the number of calls to make at once, as set by
+// SetConcurrency.
+func Concurrency() int {
+	return concurrency
+}
+
+// Run calls fn for each item, with at most limit calls in flight. Every item
+// is processed even if some calls fail; the errors of all failed calls are
+// returned together, in the order of items.
+func Run[T any](limit int, items []T, fn func(T) error) error {
+	_, err := Map(limit, items, func(item T) (struct{}, error) {
+		return struct{}{}, fn(item)
+	})
+	return err
+}
+
+// Map is like Run, and also returns the result of each call in the order of
+// items. The results of failed calls are zero values.
+func Map[T, R any](limit int, items []T, fn func(T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = fn(item)
+		}()
+	}
+	wg.Wait()
+	allErrs := new(multierror.Error)
+	for _, err := range errs {
+		if err != nil {
+			allErrs = multierror.Append(allErrs, err)
+		}
+	}
+	return results, allErrs.ErrorOrNil()
+}
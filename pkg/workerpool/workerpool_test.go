@@ -0,0 +1,57 @@
+package workerpool
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	var inFlight, maxInFlight atomic.Int32
+	results, err := Map(3, items, func(i int) (int, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		return i * 10, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60, 70, 80}, results)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+}
+
+func TestRunCollectsAllErrors(t *testing.T) {
+	var calls atomic.Int32
+	err := Run(2, []int{1, 2, 3, 4}, func(i int) error {
+		calls.Add(1)
+		if i%2 == 0 {
+			return fmt.Errorf("item %d failed", i)
+		}
+		return nil
+	})
+	assert.Equal(t, int32(4), calls.Load())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "item 2 failed")
+	assert.Contains(t, err.Error(), "item 4 failed")
+
+	err = Run(2, []int{1, 3}, func(i int) error { return nil })
+	assert.NoError(t, err)
+
+	err = Run(0, []string{"a"}, func(s string) error { return fmt.Errorf("%s", s) })
+	assert.ErrorContains(t, err, "a")
+}
+
+func TestSetConcurrency(t *testing.T) {
+	SetConcurrency(0)
+	assert.Equal(t, 1, Concurrency())
+	SetConcurrency(8)
+	assert.Equal(t, 8, Concurrency())
+	SetConcurrency(1)
+}